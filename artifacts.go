@@ -0,0 +1,127 @@
+// Copyright © 2015-2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package test
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Artifacts returns the directory for the program transcripts, daemon and
+// container logs, and packet captures of the given test; or "" without
+// -test.artifacts.dir. The directory is the test's full name within that of
+// the flag, which isn't -test.artifacts to avoid that of newer go releases.
+func Artifacts(tb testing.TB) string {
+	return artifacts(tb.Name())
+}
+
+func artifacts(name string) string {
+	if len(*ArtifactsFlag) == 0 {
+		return ""
+	}
+	dir := filepath.Join(*ArtifactsFlag, filepath.FromSlash(name))
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		Log().Print(err)
+		return ""
+	}
+	return dir
+}
+
+// Artifact creates the named file in the test's Artifacts directory. If the
+// file already exists, the name is suffixed with a unique index. Without
+// -test.artifacts.dir, Artifact returns nil, nil.
+func Artifact(tb testing.TB, name string) (*os.File, error) {
+	return createArtifact(Artifacts(tb), name)
+}
+
+func createArtifact(dir, name string) (*os.File, error) {
+	if len(dir) == 0 {
+		return nil, nil
+	}
+	ext := filepath.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	for i := 0; ; i++ {
+		fn := filepath.Join(dir, name)
+		if i > 0 {
+			fn = filepath.Join(dir, fmt.Sprint(base, ".", i, ext))
+		}
+		f, err := os.OpenFile(fn, os.O_WRONLY|os.O_CREATE|os.O_EXCL,
+			0644)
+		if !os.IsExist(err) {
+			return f, err
+		}
+	}
+}
+
+// appendArtifact opens the named artifact for append.
+func appendArtifact(tb testing.TB, name string) (*os.File, error) {
+	dir := Artifacts(tb)
+	if len(dir) == 0 {
+		return nil, nil
+	}
+	return os.OpenFile(filepath.Join(dir, name),
+		os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+}
+
+// pruneArtifacts of a passing test with -test.artifacts.prune.
+func pruneArtifacts(t *testing.T) {
+	if len(*ArtifactsFlag) == 0 || !*PruneArtifactsFlag || t.Failed() {
+		return
+	}
+	os.RemoveAll(filepath.Join(*ArtifactsFlag,
+		filepath.FromSlash(t.Name())))
+}
+
+// transcribe the Result to the test's programs.log artifact.
+func (r *Result) transcribe(tb testing.TB, netns Netns, err error) {
+	f, ferr := appendArtifact(tb, "programs.log")
+	if f == nil {
+		if ferr != nil {
+			tb.Log(ferr)
+		}
+		return
+	}
+	defer f.Close()
+	if netns.IsDefault() {
+		fmt.Fprintln(f, "$", strings.Join(r.Args, " "))
+	} else {
+		fmt.Fprintln(f, "$", netns, strings.Join(r.Args, " "))
+	}
+	io.WriteString(f, r.Stdout)
+	if len(r.Stderr) > 0 {
+		fmt.Fprintln(f, "stderr:")
+		io.WriteString(f, r.Stderr)
+	}
+	fmt.Fprintln(f, "#", r, r.Duration, r.Usage)
+	if err != nil {
+		fmt.Fprintln(f, "# error:", err)
+	}
+	fmt.Fprintln(f)
+}
+
+// Capture packets of the named interface to a pcap artifact of the test
+// until the returned program is Quit. Without -test.artifacts.dir,
+// Capture returns nil, which may still be Quit.
+// Usage:
+//
+//	defer assert.Capture("h1", "eth0").Quit()
+func (assert Assert) Capture(netns, ifname string) *Program {
+	assert.Helper()
+	dir := Artifacts(assert.TB)
+	if len(dir) == 0 {
+		return nil
+	}
+	name := ifname + ".pcap"
+	if !Netns(netns).IsDefault() {
+		name = netns + "-" + name
+	}
+	return assert.Background(Netns(netns), Quiet{}, AllowStderr{},
+		"tcpdump", "-U", "-n", "-i", ifname,
+		"-w", filepath.Join(dir, name))
+}
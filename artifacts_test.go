@@ -0,0 +1,49 @@
+// Copyright © 2015-2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// echoTester transcribes an echo of its name to the programs.log artifact.
+type echoTester string
+
+func (name echoTester) String() string { return string(name) }
+
+func (name echoTester) Test(t *testing.T) {
+	assert := Assert{t}
+	assert.Program("echo", string(name))
+	_, err := os.Stat(filepath.Join(Artifacts(t), "programs.log"))
+	assert.Nil(err)
+}
+
+func TestArtifacts(t *testing.T) {
+	assert := Assert{t}
+	dir, err := ioutil.TempDir("", "test-artifacts")
+	assert.Nil(err)
+	defer os.RemoveAll(dir)
+	defer func(dir string, prune, stepping, pausing bool) {
+		*ArtifactsFlag, *PruneArtifactsFlag = dir, prune
+		*step.flag, *Pause.flag = stepping, pausing
+	}(*ArtifactsFlag, *PruneArtifactsFlag, step.Flag(), Pause.Flag())
+	*ArtifactsFlag, *PruneArtifactsFlag = dir, false
+	step.reset()
+	Pause.reset()
+
+	Tests{echoTester("kept")}.Test(t)
+	b, err := ioutil.ReadFile(filepath.Join(dir, t.Name(), "kept",
+		"programs.log"))
+	assert.Nil(err)
+	assert.Match(string(b), "^\\$ echo kept\nkept\n# .*\n\n$")
+
+	*PruneArtifactsFlag = true
+	Tests{echoTester("pruned")}.Test(t)
+	_, err = os.Stat(filepath.Join(dir, t.Name(), "pruned"))
+	assert.True(os.IsNotExist(err))
+}
@@ -5,11 +5,7 @@
 package test
 
 import (
-	"bytes"
-	"go/build"
-	"io/ioutil"
 	"os"
-	"os/exec"
 	"regexp"
 	"strings"
 	"testing"
@@ -37,16 +33,24 @@ func (assert Assert) Commentf(format string, args ...interface{}) {
 	}
 }
 
-// If necessary, change to the dir of the given go package.
+// If necessary, change to the dir of the given go package, found in the
+// module of the working directory, its replacements, vendor directory or
+// module cache, or GOPATH; the original working directory is restored when
+// the test ends.
 func (assert Assert) Dir(name string) {
+	assert.Helper()
 	wd, err := os.Getwd()
 	assert.Nil(err)
 	if strings.HasSuffix(wd, name) {
 		return
 	}
-	pkg, err := build.Import(name, "", build.FindOnly)
-	assert.Nil(err)
-	assert.Nil(os.Chdir(pkg.Dir))
+	dir, err := packageSource(wd, name)
+	if err != nil {
+		assert.Fatal(err)
+		return
+	}
+	assert.Nil(os.Chdir(dir))
+	assert.Cleanup(func() { os.Chdir(wd) })
 }
 
 // Nil asserts that there is no error
@@ -65,7 +69,8 @@ func (assert Assert) NonNil(err error) {
 	}
 }
 
-// Error asserts that an error matches the given error, string, regex, or bool
+// Error asserts that an error matches the given error, string, Matcher (e.g.
+// regex), or bool
 // If v is true, asserts err isn't nil;
 // otherwise, if false, asserts that it's nil.
 func (assert Assert) Error(err error, v interface{}) {
@@ -79,7 +84,7 @@ func (assert Assert) Error(err error, v interface{}) {
 		if err == nil || err.Error() != t {
 			assert.Fatalf("expected %q", t)
 		}
-	case *regexp.Regexp:
+	case Matcher:
 		if err == nil || !t.MatchString(err.Error()) {
 			assert.Fatalf("expected %q", t.String())
 		}
@@ -96,11 +101,27 @@ func (assert Assert) Error(err error, v interface{}) {
 	}
 }
 
-// Equal asserts string equality.
+// Equal asserts string equality. Multi-line mismatches are shown as a
+// unified Diff from expect to s.
 func (assert Assert) Equal(s, expect string) {
 	assert.Helper()
-	if s != expect {
-		assert.Fatalf("%q\n\t!= %q", s, expect)
+	if s == expect {
+		return
+	}
+	if strings.Contains(s, "\n") || strings.Contains(expect, "\n") {
+		assert.Fatalf("mismatch\n%s", Diff(expect, s))
+		return
+	}
+	assert.Fatalf("%q\n\t!= %q", s, expect)
+}
+
+// DeepEqual asserts that got and want are deeply equal values, like maps,
+// slices, and structs parsed from program output, showing the path of each
+// mismatch (see DeepDiff).
+func (assert Assert) DeepEqual(got, want interface{}) {
+	assert.Helper()
+	if diffs := DeepDiff(want, got); len(diffs) > 0 {
+		assert.Fatalf("mismatch\n\t%s", strings.Join(diffs, "\n\t"))
 	}
 }
 
@@ -137,25 +158,28 @@ func (assert Assert) False(t bool) {
 	}
 }
 
-// Verifiy that there is no listener on named Unix socket.
-func (assert Assert) NoListener(atsockname string) {
+// Program asserts that the Program runs without error.
+func (assert Assert) Program(options ...interface{}) {
 	assert.Helper()
-	b, err := ioutil.ReadFile("/proc/net/unix")
+	p, err := Begin(assert.TB, options...)
 	if err != nil {
+		assert.Fatal(err)
 		return
 	}
-	if bytes.Index(b, []byte(atsockname)) < 0 {
-		return
-	}
-	assert.Fatal(atsockname, "in use")
+	assert.Nil(p.End())
 }
 
-// Program asserts that the Program runs without error.
-func (assert Assert) Program(options ...interface{}) {
+// Output asserts that the Program runs without error and returns its Result.
+func (assert Assert) Output(options ...interface{}) *Result {
 	assert.Helper()
 	p, err := Begin(assert.TB, options...)
+	if err != nil {
+		assert.Fatal(err)
+		return nil
+	}
+	r, err := p.Wait()
 	assert.Nil(err)
-	assert.Nil(p.End())
+	return r
 }
 
 func (assert Assert) ProgramNonFatal(options ...interface{}) bool {
@@ -168,23 +192,24 @@ func (assert Assert) ProgramNonFatal(options ...interface{}) bool {
 func (assert Assert) ProgramErr(v interface{}, options ...interface{}) {
 	assert.Helper()
 	p, err := Begin(assert.TB, options...)
-	assert.Nil(err)
-	assert.Error(p.End(), true)
+	if err != nil {
+		assert.Fatal(err)
+		return
+	}
+	assert.Error(p.End(), v)
 }
 
+// ProgramRetry asserts that the Program runs without error w/in the given
+// number of tries, a second apart.
 func (assert Assert) ProgramRetry(tries int, options ...interface{}) {
-	var err error
-	var p *Program
-	assert.Helper()
-	for try := 0; try < tries; try++ {
-		p, err = Begin(assert.TB, options...)
-		assert.Nil(err)
-		if err = p.End(); err == nil {
-			return
+	assert.Helper()
+	assert.Eventually(func() (interface{}, bool) {
+		p, err := Begin(assert.TB, options...)
+		if err == nil {
+			err = p.End()
 		}
-		time.Sleep(time.Second)
-	}
-	assert.Nil(err)
+		return err, err == nil
+	}, 0, time.Second, Attempts(tries))
 }
 
 // Background Program after asserting that it starts without error.
@@ -193,44 +218,28 @@ func (assert Assert) ProgramRetry(tries int, options ...interface{}) {
 func (assert Assert) Background(options ...interface{}) *Program {
 	assert.Helper()
 	p, err := Begin(assert.TB, options...)
-	assert.Nil(err)
-	return p
-}
-
-func (assert Assert) PingNonFatal(netns, addr string) bool {
-	xargs := []string{"ping", "-q", "-c", "1", "-W", "1", addr}
-	if len(netns) > 0 && netns != "default" {
-		xargs = append([]string{"ip", "netns", "exec", netns},
-			xargs...)
-	}
-	if exec.Command(xargs[0], xargs[1:]...).Run() == nil {
-		return true
-	} else {
-		return false
+	if err != nil {
+		assert.Fatal(err)
+		return nil
 	}
-
+	return p
 }
 
-// Assert ping response to given address w/in 3sec.
-func (assert Assert) Ping(netns, addr string) {
-	const period = 250 * time.Millisecond
-	assert.Helper()
-	xargs := []string{"ping", "-q", "-c", "1", "-W", "1", addr}
-	if len(netns) > 0 && netns != "default" {
-		xargs = append([]string{"ip", "netns", "exec", netns},
-			xargs...)
-	}
-	if *VVV {
-		assert.Log(xargs)
-	}
-	for t := 1 * (time.Second / period); t != 0; t-- {
-		if exec.Command(xargs[0], xargs[1:]...).Run() == nil {
-			return
-		}
-		time.Sleep(period)
-	}
-	if false {
-		Pause.Prompt("Failed ", netns, " ping ", addr)
-	}
-	assert.Fatalf("%s no response", addr)
+// Expect asserts that the Program Stdout matches the pattern w/in timeout
+// and returns the match and any submatches (see Program.Expect).
+// Usage:
+//
+//	p := assert.Background(...)
+//	defer p.Quit()
+//	assert.Expect(p, "ready", 10*time.Second)
+func (assert Assert) Expect(p *Program, pattern string,
+	timeout time.Duration) []string {
+	assert.Helper()
+	if p == nil {
+		assert.Fatal("no program")
+		return nil
+	}
+	match, err := p.Expect(regexp.MustCompile(pattern), timeout)
+	assert.Nil(err)
+	return match
 }
@@ -0,0 +1,101 @@
+// Copyright © 2015-2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package test
+
+import (
+	"bytes"
+	"sync"
+)
+
+// syncBuffer is a bytes.Buffer that may be read while written by a running
+// program. Readers may wait for the next write or Close.
+type syncBuffer struct {
+	mutex   sync.Mutex
+	buf     bytes.Buffer
+	changed chan struct{}
+	closed  bool
+	// if non-zero, only retain this many of the most recently written
+	// bytes; so, this may not be used with Since
+	limit int
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	n, err := b.buf.Write(p)
+	if b.limit > 0 && b.buf.Len() > b.limit {
+		b.buf.Next(b.buf.Len() - b.limit)
+	}
+	b.signal()
+	return n, err
+}
+
+func (b *syncBuffer) WriteRune(r rune) (int, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	n, err := b.buf.WriteRune(r)
+	b.signal()
+	return n, err
+}
+
+// Close signals waiters that there won't be any more writes.
+func (b *syncBuffer) Close() error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.closed = true
+	b.signal()
+	return nil
+}
+
+// Bytes returns a copy of the buffered content.
+func (b *syncBuffer) Bytes() []byte {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return append([]byte(nil), b.buf.Bytes()...)
+}
+
+func (b *syncBuffer) String() string {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.buf.String()
+}
+
+func (b *syncBuffer) Len() int {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.buf.Len()
+}
+
+func (b *syncBuffer) Reset() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.buf.Reset()
+}
+
+// Since returns a copy of the content from offset i; a channel that's closed
+// with the next write or Close; and whether the buffer is already closed.
+func (b *syncBuffer) Since(i int) ([]byte, <-chan struct{}, bool) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	var content []byte
+	if i < b.buf.Len() {
+		content = append(content, b.buf.Bytes()[i:]...)
+	}
+	if b.changed == nil {
+		b.changed = make(chan struct{})
+		if b.closed {
+			close(b.changed)
+		}
+	}
+	return content, b.changed, b.closed
+}
+
+// signal waiters; must be called with mutex locked
+func (b *syncBuffer) signal() {
+	if b.changed != nil {
+		close(b.changed)
+		b.changed = nil
+	}
+}
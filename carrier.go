@@ -8,28 +8,27 @@ import (
 	"bytes"
 	"fmt"
 	"os/exec"
-	"path/filepath"
 	"time"
 )
 
-// Assert that named interface has carrier w/in 3sec.
+// CarrierTimeout is the time allowed for an interface to have carrier.
+const CarrierTimeout = 3 * time.Second
+
+// Carrier returns nil if named interface has carrier w/in CarrierTimeout.
+//
+// The link is shown by netlink rather than /sys/class/net because the
+// latter reflects the namespace of the sysfs mount, not of the program.
 func Carrier(netns, ifname string) error {
-	const period = 250 * time.Millisecond
-	fn := filepath.Join("/sys/class/net", ifname, "carrier")
-	xargs := []string{"cat", fn}
-	if len(netns) > 0 && netns != "default" {
-		xargs = append([]string{"ip", "netns", "exec", netns},
-			xargs...)
-	}
-	for t := 3 * (time.Second / period); t != 0; t-- {
-		output, err := exec.Command(xargs[0], xargs[1:]...).Output()
-		if err != nil {
-			return err
-		}
-		if bytes.Equal(output, []byte("1\n")) {
-			return nil
-		}
-		time.Sleep(period)
+	var err error
+	xargs := []string{"ip", "-o", "link", "show", "dev", ifname}
+	if Eventually(func() (interface{}, bool) {
+		var output []byte
+		cmd := exec.Command(xargs[0], xargs[1:]...)
+		output, err = Netns(netns).Output(cmd)
+		return string(output), err != nil ||
+			bytes.Contains(output, []byte("LOWER_UP"))
+	}, CarrierTimeout, 250*time.Millisecond) != nil {
+		return fmt.Errorf("%s no carrier", ifname)
 	}
-	return fmt.Errorf("%s no carrier", ifname)
+	return err
 }
@@ -0,0 +1,411 @@
+// Copyright © 2015-2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package test
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// ReadyTimeout is the default duration to wait for Daemon Ready probes.
+const ReadyTimeout = 10 * time.Second
+
+// DaemonOutputLimit is the number of the most recent bytes of Stdout and
+// Stderr retained in memory by a Daemon; use -test.artifacts.dir to log
+// all output.
+const DaemonOutputLimit = 1 << 20
+
+// DefaultMaxRestarts limits the restarts of a supervised Daemon unless
+// overridden by its MaxRestarts.
+const DefaultMaxRestarts = 3
+
+// Restart is the policy of a Daemon that exits before Stop.
+type Restart int
+
+const (
+	RestartNever Restart = iota
+	RestartOnFailure
+	RestartAlways
+)
+
+// DaemonEvent is an entry in the history of a Daemon.
+type DaemonEvent struct {
+	Time time.Time
+	Pid  int
+	What string
+}
+
+func (ev DaemonEvent) String() string {
+	return fmt.Sprint(ev.Time.Format("15:04:05.000"), " ", ev.Pid, " ",
+		ev.What)
+}
+
+// A Daemon is a background program started and defer stopped from a TestMain.
+//
+// The Daemon is supervised so that if it exits before Stop, the currently
+// running test fails with the exit status and tail of the daemon's output.
+type Daemon struct {
+	// Ready probes are polled after Start until all succeed, or for
+	// ReadyTimeout, instead of guessing with a sleep.
+	Ready []Probe
+	// ReadyTimeout overrides the default of the same name.
+	ReadyTimeout time.Duration
+	// Restart the daemon if it exits before Stop; default, RestartNever.
+	Restart Restart
+	// MaxRestarts overrides DefaultMaxRestarts.
+	MaxRestarts int
+
+	mutex    sync.Mutex
+	args     []string
+	cmd      *exec.Cmd
+	stdout   *syncBuffer
+	stderr   *syncBuffer
+	stdw     io.Writer // to stdout and any log
+	stderrw  io.Writer // to stderr and any log
+	log      *os.File  // artifact
+	lws      []*lineWriter
+	start    time.Time
+	result   *Result
+	stopping bool
+	restarts int
+	events   []DaemonEvent
+
+	done    chan struct{}
+	waitErr error
+
+	take *Take // of -test.record or -test.replay
+}
+
+func (d *Daemon) Pid() int {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	if d.cmd == nil {
+		return 0
+	}
+	return d.cmd.Process.Pid
+}
+
+// Start the daemon program, wait for it to be Ready, and panic if error.
+func (d *Daemon) Start(args ...string) {
+	d.args = args
+	d.stdout = &syncBuffer{limit: DaemonOutputLimit}
+	d.stderr = &syncBuffer{limit: DaemonOutputLimit}
+	d.stdw, d.stderrw = io.Writer(d.stdout), io.Writer(d.stderr)
+	f, err := createArtifact(artifacts("daemons"),
+		filepath.Base(args[0])+".log")
+	if err != nil {
+		Log().Print(err)
+	} else if f != nil {
+		d.log = f
+		d.stdw = io.MultiWriter(d.stdout, f)
+		d.stderrw = io.MultiWriter(d.stderr, f)
+	}
+	if *StreamFlag {
+		log := func(args ...interface{}) { Log().Print(args...) }
+		d.lws = []*lineWriter{
+			{w: d.stdw, log: log, pid: d.Pid, name: "stdout"},
+			{w: d.stderrw, log: log, pid: d.Pid, name: "stderr"},
+		}
+		d.stdw, d.stderrw = d.lws[0], d.lws[1]
+	}
+	if *VVV {
+		Log().Output(2, strings.TrimSpace(fmt.Sprintln(args)))
+	}
+	d.done = make(chan struct{})
+	if d.take, err = replaying("TestMain", args, ""); err != nil {
+		panic(err)
+	} else if d.take != nil {
+		io.WriteString(d.stdw, d.take.Stdout)
+		io.WriteString(d.stderrw, d.take.Stderr)
+		d.stdout.Close()
+		d.stderr.Close()
+		close(d.done)
+		return
+	}
+	if d.take, err = recording("TestMain", args, ""); err != nil {
+		panic(err)
+	}
+	d.start = time.Now()
+	if err := d.launch(); err != nil {
+		panic(fmt.Errorf("%v: %v", args, err))
+	}
+	go d.supervise()
+	if len(d.Ready) > 0 {
+		timeout := d.ReadyTimeout
+		if timeout == 0 {
+			timeout = ReadyTimeout
+		}
+		if err := d.WaitReady(timeout, d.Ready...); err != nil {
+			d.Stop()
+			panic(err)
+		}
+	}
+}
+
+// launch a new instance of the daemon program.
+func (d *Daemon) launch() error {
+	cmd := exec.Command(d.args[0], d.args[1:]...)
+	cmd.Stdout = d.stdw
+	cmd.Stderr = d.stderrw
+	setpgid(cmd)
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	if d.stopping {
+		return errors.New("stopping")
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	d.cmd = cmd
+	d.event(cmd.Process.Pid, "started")
+	if d.log != nil {
+		fmt.Fprintln(d.log, "#", cmd.Process.Pid, "started", cmd.Args)
+	}
+	return nil
+}
+
+// supervise waits for the daemon to exit. If this is before Stop, it fails
+// the current test and restarts the daemon according to its policy.
+// Otherwise, it closes the output buffers to signal any LogLine probe.
+func (d *Daemon) supervise() {
+	for {
+		d.mutex.Lock()
+		cmd := d.cmd
+		d.mutex.Unlock()
+		err := cmd.Wait()
+		status := "exited"
+		if err != nil {
+			status = err.Error()
+		}
+		d.mutex.Lock()
+		d.event(cmd.Process.Pid, status)
+		stopping := d.stopping
+		d.mutex.Unlock()
+		if stopping {
+			d.waitErr = err
+			break
+		}
+		failRunning(fmt.Sprintf("daemon %v: %s\n%s", d.args, status,
+			tail(d.stdout.String()+d.stderr.String(), 10)))
+		if !d.restart(cmd, err) {
+			d.waitErr = err
+			break
+		}
+	}
+	d.stdout.Close()
+	d.stderr.Close()
+	close(d.done)
+}
+
+// restart an unexpectedly exited daemon if permitted by its policy; first
+// killing any survivors of the exited instance's process group.
+func (d *Daemon) restart(cmd *exec.Cmd, err error) bool {
+	max := d.MaxRestarts
+	if max == 0 {
+		max = DefaultMaxRestarts
+	}
+	switch {
+	case d.Restart == RestartNever:
+		return false
+	case d.Restart == RestartOnFailure && err == nil:
+		return false
+	case d.restarts >= max:
+		d.mutex.Lock()
+		d.event(0, fmt.Sprint("not restarted after ", d.restarts))
+		d.mutex.Unlock()
+		return false
+	}
+	d.restarts++
+	if survivors := reapGroup(cmd.Process.Pid); len(survivors) > 0 {
+		d.mutex.Lock()
+		d.event(cmd.Process.Pid, fmt.Sprint("killed survivors ",
+			survivors))
+		d.mutex.Unlock()
+	}
+	if err := d.launch(); err != nil {
+		d.mutex.Lock()
+		d.event(0, fmt.Sprint("restart: ", err))
+		d.mutex.Unlock()
+		return false
+	}
+	return true
+}
+
+// event appends to the daemon history; must be called with mutex locked
+func (d *Daemon) event(pid int, what string) {
+	d.events = append(d.events, DaemonEvent{time.Now(), pid, what})
+}
+
+// Events returns the history of the daemon's starts and exits.
+func (d *Daemon) Events() []DaemonEvent {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	return append([]DaemonEvent(nil), d.events...)
+}
+
+// Exited is true if the daemon is no longer running nor will be restarted.
+func (d *Daemon) Exited() bool {
+	select {
+	case <-d.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// WaitReady polls the given probes until all succeed, the daemon exits, or
+// the timeout expires.
+func (d *Daemon) WaitReady(timeout time.Duration, probes ...Probe) error {
+	err := WaitFor(timeout, func() error {
+		if d.Exited() {
+			return errExited
+		}
+		for _, probe := range probes {
+			if err := probe(); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err == errExited {
+		if d.waitErr == nil {
+			return fmt.Errorf("%v: exited", d.args)
+		}
+		return fmt.Errorf("%v: %v", d.args, d.waitErr)
+	}
+	if err != nil {
+		return fmt.Errorf("%v: not ready: %v", d.args, err)
+	}
+	return nil
+}
+
+// LogLine probes for a match of the daemon output, e.g. "listening".
+func (d *Daemon) LogLine(pattern string) Probe {
+	re := regexp.MustCompile(pattern)
+	return func() error {
+		if re.MatchString(d.stdout.String()) ||
+			re.MatchString(d.stderr.String()) {
+			return nil
+		}
+		return fmt.Errorf("no log line %q", re)
+	}
+}
+
+// Stop the running daemon's process group with a TERM, INT, then KILL signal
+func (d *Daemon) Stop() {
+	var err error
+	d.mutex.Lock()
+	cmd, take := d.cmd, d.take
+	if cmd == nil && take != nil {
+		d.mutex.Unlock()
+		d.result = take.result()
+		return
+	}
+	d.stopping = true
+	d.mutex.Unlock()
+	sig := syscall.SIGTERM
+	timeout := 3 * time.Second
+	tm := time.NewTimer(timeout)
+	signalGroup(cmd, sig)
+again:
+	select {
+	case <-d.done:
+		err = d.waitErr
+	case <-tm.C:
+		switch sig {
+		case syscall.SIGKILL:
+			Log().Output(2, "won't die!")
+		case syscall.SIGINT:
+			err = syscall.ETIME
+			sig = syscall.SIGKILL
+			timeout *= 2
+			signalGroup(cmd, sig)
+			tm.Reset(timeout)
+			goto again
+		case syscall.SIGTERM:
+			sig = syscall.SIGINT
+			timeout *= 2
+			signalGroup(cmd, sig)
+			tm.Reset(timeout)
+			goto again
+		}
+	}
+	tm.Stop()
+	if survivors := reapGroup(cmd.Process.Pid); len(survivors) > 0 {
+		Log().Output(2, fmt.Sprint("killed ", cmd.Process.Pid,
+			" survivors ", survivors))
+	}
+	for _, lw := range d.lws {
+		lw.Flush()
+	}
+	if d.Exited() {
+		d.result = newResult(cmd, d.start)
+	} else {
+		d.result = &Result{
+			Args:     cmd.Args,
+			ExitCode: -1,
+			Duration: time.Since(d.start),
+		}
+	}
+	d.result.Stdout = d.stdout.String()
+	d.result.Stderr = d.stderr.String()
+	if take != nil {
+		take.record(d.result)
+		if xerr := save("TestMain"); xerr != nil {
+			Log().Print(xerr)
+		}
+	}
+	if events := d.Events(); *VV || len(events) > 2 {
+		s := fmt.Sprint(d.args, " events:")
+		for _, ev := range events {
+			s += fmt.Sprint("\n\t", ev)
+		}
+		Log().Output(2, s)
+	}
+	if d.log != nil {
+		for _, ev := range d.Events() {
+			fmt.Fprintln(d.log, "#", ev)
+		}
+		d.log.Close()
+	}
+	if err != nil {
+		s := err.Error()
+		for _, b := range []*syncBuffer{
+			d.stdout,
+			d.stderr,
+		} {
+			if b.Len() > 0 {
+				s += "\n"
+				s += b.String()
+			}
+		}
+		Log().Output(2, strings.TrimSpace(s))
+	} else if *VV && d.lws == nil && d.stdout.Len() > 0 {
+		Log().Output(2, d.stdout.String())
+	}
+}
+
+// Result of the Daemon after Stop, including its resource Usage.
+func (d *Daemon) Result() *Result {
+	return d.result
+}
+
+// tail returns the last n lines of s.
+func tail(s string, n int) string {
+	lines := strings.Split(strings.TrimRight(s, "\n"), "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "\n")
+}
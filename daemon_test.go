@@ -0,0 +1,113 @@
+// Copyright © 2015-2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDaemonReady(t *testing.T) {
+	assert := Assert{t}
+	d := new(Daemon)
+	d.Ready = []Probe{d.LogLine("(?m)^ready$")}
+	d.Start("sh", "-c", "sleep 0.2; echo ready; exec sleep 60")
+	assert.Nil(d.LogLine("ready")())
+	assert.NonNil(d.LogLine("never")())
+	err := d.WaitReady(200*time.Millisecond, d.LogLine("never"))
+	assert.Error(err, Contains("not ready"))
+	d.Stop()
+	assert.True(d.Exited())
+
+	d = new(Daemon)
+	d.Start("sh", "-c", "sleep 0.2; exit 3")
+	err = d.WaitReady(5*time.Second, d.LogLine("never"))
+	assert.Error(err, Contains("exit status 3"))
+	d.Stop()
+}
+
+func TestDaemonExit(t *testing.T) {
+	assert := Assert{t}
+	tb := &fakeTB{TB: t}
+	defer running(tb)()
+	d := new(Daemon)
+	d.Start("sh", "-c", "echo bye; exit 1")
+	assert.Nil(WaitFor(5*time.Second, func() error {
+		if !d.Exited() {
+			return errors.New("running")
+		}
+		return nil
+	}))
+	assert.True(tb.failed)
+	assert.True(len(tb.out) == 1)
+	assert.Match(tb.out[0], "exit status 1\nbye")
+	d.Stop()
+	assert.True(d.Result().ExitCode == 1)
+}
+
+func TestDaemonRestart(t *testing.T) {
+	assert := Assert{t}
+	tb := &fakeTB{TB: t}
+	defer running(tb)()
+	d := &Daemon{Restart: RestartOnFailure, MaxRestarts: 2}
+	d.Start("sh", "-c", "sleep 60 >/dev/null 2>&1 & exit 1")
+	assert.Nil(WaitFor(5*time.Second, func() error {
+		if !d.Exited() {
+			return errors.New("running")
+		}
+		return nil
+	}))
+	d.Stop()
+	assert.True(len(tb.out) == 3)
+	assert.Equal(daemonHistory(d), `started
+exit status 1
+killed survivors
+started
+exit status 1
+killed survivors
+started
+exit status 1
+not restarted after 2`)
+}
+
+func TestDaemonStopRestarted(t *testing.T) {
+	assert := Assert{t}
+	tb := &fakeTB{TB: t}
+	defer running(tb)()
+	dir, err := ioutil.TempDir("", "test-daemon")
+	assert.Nil(err)
+	defer os.RemoveAll(dir)
+	once := filepath.Join(dir, "once")
+	d := &Daemon{Restart: RestartAlways}
+	d.Ready = []Probe{d.LogLine("ready")}
+	d.Start("sh", "-c", "test -e "+once+" || { touch "+once+"; exit 0; }"+
+		"; echo ready; exec sleep 60")
+	d.Stop()
+	assert.True(tb.failed)
+	assert.True(d.Exited())
+	assert.True(d.Result().ExitCode == -1)
+	assert.Equal(daemonHistory(d), `started
+exited
+started
+signal: terminated`)
+}
+
+// daemonHistory returns the lines of d's Events without times, pids, or
+// survivors.
+func daemonHistory(d *Daemon) string {
+	re := regexp.MustCompile(` \[.*\]$`)
+	var history []string
+	for _, ev := range d.Events() {
+		history = append(history,
+			strings.TrimSpace(re.ReplaceAllString(ev.What, "")))
+	}
+	return strings.Join(history, "\n")
+}
@@ -0,0 +1,178 @@
+// Copyright © 2015-2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// JSON decodes the Stdout of the Result into v.
+func (r *Result) JSON(v interface{}) error {
+	if err := json.Unmarshal([]byte(r.Stdout), v); err != nil {
+		return fmt.Errorf("%v: %v", r.Args, err)
+	}
+	return nil
+}
+
+// YAML decodes the Stdout of the Result into v.
+func (r *Result) YAML(v interface{}) error {
+	if err := yaml.Unmarshal([]byte(r.Stdout), v); err != nil {
+		return fmt.Errorf("%v: %v", r.Args, err)
+	}
+	return nil
+}
+
+// JSON asserts that the Program runs without error and decodes its Stdout
+// into v.
+// Usage:
+//
+//	var links []struct {
+//		Ifname    string
+//		Operstate string
+//	}
+//	assert.JSON(&links, Netns(ns), "ip", "-j", "link", "show")
+func (assert Assert) JSON(v interface{}, options ...interface{}) {
+	assert.Helper()
+	decodeOutput(assert, (*Result).JSON, v, options)
+}
+
+// YAML asserts that the Program runs without error and decodes its Stdout
+// into v.
+func (assert Assert) YAML(v interface{}, options ...interface{}) {
+	assert.Helper()
+	decodeOutput(assert, (*Result).YAML, v, options)
+}
+
+// decodeOutput is Output, but only decodes the Result if the Program ran
+// without error; so, a Soft failure isn't also reported as that of decode.
+func decodeOutput(assert Assert, decode func(*Result, interface{}) error,
+	v interface{}, options []interface{}) {
+	assert.Helper()
+	p, err := Begin(assert.TB, options...)
+	if err != nil {
+		assert.Fatal(err)
+		return
+	}
+	r, err := p.Wait()
+	if err != nil {
+		assert.Fatal(err)
+		return
+	}
+	assert.Nil(decode(r, v))
+}
+
+// Path asserts that m matches the value at path within v (see Lookup).
+// Usage:
+//
+//	assert.Path(links, "[0].operstate", Regexp("UP|UNKNOWN"))
+func (assert Assert) Path(v interface{}, path string, m Matcher) {
+	assert.Helper()
+	x, err := Lookup(v, path)
+	if err != nil {
+		assert.Fatal(err)
+		return
+	}
+	if s := format(x); !m.MatchString(s) {
+		assert.Fatalf("%s: %q doesn't match %v", path, s, m)
+	}
+}
+
+// Lookup the value at path within v, either a tree of maps and slices like
+// that decoded from JSON or YAML into an interface{}, or a decoded struct.
+// The path has dot separated keys and bracketed indices, e.g.
+// "routes[1].gateway". Struct fields are named by their json tag or,
+// without case, their Go name.
+func Lookup(v interface{}, path string) (interface{}, error) {
+	at := ""
+	for len(path) > 0 {
+		var key string
+		switch path[0] {
+		case '.':
+			path = path[1:]
+			continue
+		case '[':
+			i := strings.IndexByte(path, ']')
+			if i < 0 {
+				return nil, fmt.Errorf("%s: unterminated index", path)
+			}
+			key, path = path[:i+1], path[i+1:]
+			n, err := strconv.Atoi(key[1 : len(key)-1])
+			if err != nil {
+				return nil, fmt.Errorf("%s%s: %v", at, key, err)
+			}
+			x, found := index(reflect.ValueOf(v), n)
+			if !found {
+				return nil, fmt.Errorf("%s%s: not found", at, key)
+			}
+			v = x
+		default:
+			i := strings.IndexAny(path, ".[")
+			if i < 0 {
+				i = len(path)
+			}
+			key, path = path[:i], path[i:]
+			x, found := field(reflect.ValueOf(v), key)
+			if len(at) > 0 {
+				key = "." + key
+			}
+			if !found {
+				return nil, fmt.Errorf("%s%s: not found", at, key)
+			}
+			v = x
+		}
+		at += key
+	}
+	return v, nil
+}
+
+func index(v reflect.Value, n int) (interface{}, bool) {
+	v = indirect(v)
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		if n >= 0 && n < v.Len() {
+			return v.Index(n).Interface(), true
+		}
+	}
+	return nil, false
+}
+
+func field(v reflect.Value, key string) (interface{}, bool) {
+	v = indirect(v)
+	switch v.Kind() {
+	case reflect.Map:
+		for _, k := range v.MapKeys() {
+			if fmt.Sprint(k.Interface()) == key {
+				return v.MapIndex(k).Interface(), true
+			}
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			f := v.Type().Field(i)
+			if len(f.PkgPath) > 0 {
+				continue
+			}
+			tag := strings.Split(f.Tag.Get("json"), ",")[0]
+			if tag == key || (len(tag) == 0 &&
+				strings.EqualFold(f.Name, key)) {
+				return v.Field(i).Interface(), true
+			}
+		}
+	}
+	return nil, false
+}
+
+func indirect(v reflect.Value) reflect.Value {
+	for v.IsValid() &&
+		(v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) {
+		v = v.Elem()
+	}
+	return v
+}
@@ -0,0 +1,43 @@
+// Copyright © 2015-2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package test
+
+import "testing"
+
+func TestDecode(t *testing.T) {
+	assert := Assert{t}
+	type link struct {
+		Ifname string
+		MTU    int `json:"mtu"`
+		Flags  []string
+	}
+	var links []link
+	assert.JSON(&links, "echo",
+		`[{"ifname":"lo","mtu":65536,"flags":["LOOPBACK","UP"]}]`)
+	assert.DeepEqual(links, []link{{"lo", 65536, []string{"LOOPBACK", "UP"}}})
+	assert.Path(links, "[0].mtu", Eq(65536))
+	assert.Path(&links, "[0].flags[1]", Contains("UP"))
+
+	var v interface{}
+	assert.YAML(&v, "printf", `routes:\n- prefix: 10.1.0.0/24\n  gw: 10.1.0.1\n`)
+	assert.Path(v, "routes[0].gw", Regexp(`^10\.1\.0\.1$`))
+	_, err := Lookup(v, "routes[1]")
+	assert.Error(err, "routes[1]: not found")
+
+	r, err := Exec("echo", "{")
+	assert.Nil(err)
+	assert.NonNil(r.JSON(&v))
+}
+
+func TestDecodeSoft(t *testing.T) {
+	tb := &fakeTB{TB: t}
+	assert := Assert{tb}.Soft()
+	var v interface{}
+	assert.JSON(&v, "sh", "-c", "echo {; exit 1")
+	assert.YAML(&v, "sh", "-c", "echo '['; exit 1")
+	if n := len(assert.TB.(*soft).failures); n != 2 {
+		t.Fatal(n, "failures, not 2")
+	}
+}
@@ -0,0 +1,335 @@
+// Copyright © 2015-2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package test
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// DiffContext is the number of unchanged lines surrounding each hunk of a
+// unified Diff.
+const DiffContext = 3
+
+// Diff returns a unified diff of the lines of want and got; or "" if these
+// are the same. Whitespace of the changed lines is made visible with tabs as
+// →, trailing spaces as ·, and carriage returns as ␍.
+func Diff(want, got string) string {
+	if want == got {
+		return ""
+	}
+	a, b := lines(want), lines(got)
+	edits := diffLines(a, b)
+	s := "--- want\n+++ got\n"
+	for _, h := range hunks(edits) {
+		s += h
+	}
+	if len(want) > 0 && len(got) > 0 &&
+		strings.HasSuffix(want, "\n") != strings.HasSuffix(got, "\n") {
+		s += "\\ newline at end of file differs\n"
+	}
+	return s
+}
+
+// lines splits s after each newline.
+func lines(s string) []string {
+	if len(s) == 0 {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+type edit struct {
+	op   byte // ' ', '-', or '+'
+	line string
+}
+
+// maxDiffEdits bounds the search of diffLines, and so its memory; beyond
+// this many edits, the changed lines are replaced as a whole.
+const maxDiffEdits = 1000
+
+// diffLines returns the shortest edit script from a to b after trimming their
+// common prefix and suffix.
+func diffLines(a, b []string) []edit {
+	var edits []edit
+	i := 0
+	for i < len(a) && i < len(b) && a[i] == b[i] {
+		edits = append(edits, edit{' ', a[i]})
+		i++
+	}
+	j := 0
+	for j < len(a)-i && j < len(b)-i &&
+		a[len(a)-1-j] == b[len(b)-1-j] {
+		j++
+	}
+	ma, mb := a[i:len(a)-j], b[i:len(b)-j]
+	if middle, ok := myers(ma, mb); ok {
+		edits = append(edits, middle...)
+	} else {
+		for _, line := range ma {
+			edits = append(edits, edit{'-', line})
+		}
+		for _, line := range mb {
+			edits = append(edits, edit{'+', line})
+		}
+	}
+	for _, line := range a[len(a)-j:] {
+		edits = append(edits, edit{' ', line})
+	}
+	return edits
+}
+
+// myers returns the shortest edit script from a to b with the greedy
+// algorithm of Myers' "An O(ND) Difference Algorithm and Its Variations";
+// or false if this exceeds maxDiffEdits. Each step d retains only the 2d+1
+// diagonals that it may reach for the backtrack.
+func myers(a, b []string) ([]edit, bool) {
+	n, m := len(a), len(b)
+	max := n + m
+	v := make([]int, 2*max+2)
+	var trace [][]int
+search:
+	for d := 0; d <= max; d++ {
+		if d > maxDiffEdits {
+			return nil, false
+		}
+		trace = append(trace, append([]int(nil), v[max-d:max+d+1]...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[max+k-1] < v[max+k+1]) {
+				x = v[max+k+1]
+			} else {
+				x = v[max+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[max+k] = x
+			if x >= n && y >= m {
+				break search
+			}
+		}
+	}
+	var edits []edit
+	x, y := n, m
+	for d := len(trace) - 1; d > 0; d-- {
+		// trace[d][d+k] is v[k] at the start of step d
+		v := trace[d]
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && v[d+k-1] < v[d+k+1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := v[d+prevK]
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			edits = append(edits, edit{' ', a[x-1]})
+			x--
+			y--
+		}
+		if x == prevX {
+			edits = append(edits, edit{'+', b[y-1]})
+		} else {
+			edits = append(edits, edit{'-', a[x-1]})
+		}
+		x, y = prevX, prevY
+	}
+	for ; x > 0; x-- {
+		edits = append(edits, edit{' ', a[x-1]})
+	}
+	for i, j := 0, len(edits)-1; i < j; i, j = i+1, j-1 {
+		edits[i], edits[j] = edits[j], edits[i]
+	}
+	return edits, true
+}
+
+// hunks formats the edits as unified diff hunks with DiffContext lines.
+func hunks(edits []edit) []string {
+	var hs []string
+	for i := 0; i < len(edits); {
+		if edits[i].op == ' ' {
+			i++
+			continue
+		}
+		// extend the hunk through changes separated by no more than
+		// twice the context
+		start := i - DiffContext
+		if start < 0 {
+			start = 0
+		}
+		end := i
+		for j := i; j < len(edits) && j-end <= 2*DiffContext; j++ {
+			if edits[j].op != ' ' {
+				end = j
+			}
+		}
+		stop := end + DiffContext + 1
+		if stop > len(edits) {
+			stop = len(edits)
+		}
+		hs = append(hs, hunk(edits, start, stop))
+		i = stop
+	}
+	return hs
+}
+
+func hunk(edits []edit, start, stop int) string {
+	// line numbers of a and b preceding the hunk
+	var ai, bi int
+	for _, e := range edits[:start] {
+		if e.op != '+' {
+			ai++
+		}
+		if e.op != '-' {
+			bi++
+		}
+	}
+	var an, bn int
+	body := ""
+	for _, e := range edits[start:stop] {
+		if e.op != '+' {
+			an++
+		}
+		if e.op != '-' {
+			bn++
+		}
+		if e.op == ' ' {
+			body += " " + e.line + "\n"
+		} else {
+			body += string(e.op) + visible(e.line) + "\n"
+		}
+	}
+	return fmt.Sprintf("@@ -%s +%s @@\n%s", span(ai, an), span(bi, bn), body)
+}
+
+// span formats the start line and count of a hunk range.
+func span(start, count int) string {
+	if count == 0 {
+		return fmt.Sprint(start, ",0")
+	}
+	if count == 1 {
+		return fmt.Sprint(start + 1)
+	}
+	return fmt.Sprint(start+1, ",", count)
+}
+
+// visible renders the tabs, carriage returns and trailing spaces of a
+// changed line so that whitespace differences stand out.
+func visible(line string) string {
+	trimmed := strings.TrimRight(line, " ")
+	line = trimmed + strings.Repeat("·", len(line)-len(trimmed))
+	line = strings.Replace(line, "\t", "→", -1)
+	return strings.Replace(line, "\r", "␍", -1)
+}
+
+// DeepDiff returns the paths and values of want and got that differ; or nil
+// if these are deeply equal. Unexported struct fields are ignored.
+func DeepDiff(want, got interface{}) []string {
+	var diffs []string
+	deepDiff(&diffs, "", reflect.ValueOf(want), reflect.ValueOf(got))
+	return diffs
+}
+
+func deepDiff(diffs *[]string, path string, want, got reflect.Value) {
+	report := func(format string, args ...interface{}) {
+		p := path
+		if len(p) == 0 {
+			p = "."
+		}
+		*diffs = append(*diffs, p+": "+fmt.Sprintf(format, args...))
+	}
+	if !want.IsValid() || !got.IsValid() {
+		if want.IsValid() != got.IsValid() {
+			report("want %s, got %s", show(want), show(got))
+		}
+		return
+	}
+	if want.Type() != got.Type() {
+		report("want %s, got %s", want.Type(), got.Type())
+		return
+	}
+	switch want.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if want.IsNil() || got.IsNil() {
+			if want.IsNil() != got.IsNil() {
+				report("want %s, got %s", show(want), show(got))
+			}
+			return
+		}
+		deepDiff(diffs, path, want.Elem(), got.Elem())
+	case reflect.Struct:
+		for i := 0; i < want.NumField(); i++ {
+			f := want.Type().Field(i)
+			if len(f.PkgPath) > 0 {
+				continue
+			}
+			deepDiff(diffs, path+"."+f.Name, want.Field(i),
+				got.Field(i))
+		}
+	case reflect.Slice, reflect.Array:
+		n := want.Len()
+		if got.Len() > n {
+			n = got.Len()
+		}
+		for i := 0; i < n; i++ {
+			p := fmt.Sprint(path, "[", i, "]")
+			switch {
+			case i >= got.Len():
+				*diffs = append(*diffs, p+": missing "+
+					show(want.Index(i)))
+			case i >= want.Len():
+				*diffs = append(*diffs, p+": extra "+
+					show(got.Index(i)))
+			default:
+				deepDiff(diffs, p, want.Index(i), got.Index(i))
+			}
+		}
+	case reflect.Map:
+		keys := append(want.MapKeys(), got.MapKeys()...)
+		sort.Slice(keys, func(i, j int) bool {
+			return fmt.Sprint(keys[i]) < fmt.Sprint(keys[j])
+		})
+		for i, k := range keys {
+			if i > 0 && fmt.Sprint(k) == fmt.Sprint(keys[i-1]) {
+				continue
+			}
+			p := fmt.Sprintf("%s[%#v]", path, k.Interface())
+			wv, gv := want.MapIndex(k), got.MapIndex(k)
+			switch {
+			case !gv.IsValid():
+				*diffs = append(*diffs, p+": missing "+show(wv))
+			case !wv.IsValid():
+				*diffs = append(*diffs, p+": extra "+show(gv))
+			default:
+				deepDiff(diffs, p, wv, gv)
+			}
+		}
+	default:
+		if want.CanInterface() &&
+			!reflect.DeepEqual(want.Interface(), got.Interface()) {
+			report("want %s, got %s", show(want), show(got))
+		}
+	}
+}
+
+// show a value, quoting strings.
+func show(v reflect.Value) string {
+	if !v.IsValid() {
+		return "nil"
+	}
+	if !v.CanInterface() {
+		return v.Type().String()
+	}
+	if v.Kind() == reflect.String {
+		return fmt.Sprintf("%q", v.Interface())
+	}
+	return fmt.Sprintf("%+v", v.Interface())
+}
@@ -0,0 +1,112 @@
+// Copyright © 2015-2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	assert := Assert{t}
+	assert.Equal(Diff("a\nb\n", "a\nb\n"), "")
+	assert.Equal(Diff("a\nb\nc\n", "a\nx\nc\n"), `--- want
++++ got
+@@ -1,3 +1,3 @@
+ a
+-b
++x
+ c
+`)
+	assert.Equal(Diff("", "a\n"), `--- want
++++ got
+@@ -0,0 +1 @@
++a
+`)
+	assert.Equal(Diff("1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n",
+		"1\n2\n3\n4\n5\nsix\n7\n8\n9\n10\n11\n12\n13\n"), `--- want
++++ got
+@@ -3,7 +3,7 @@
+ 3
+ 4
+ 5
+-6
++six
+ 7
+ 8
+ 9
+@@ -10,3 +10,4 @@
+ 10
+ 11
+ 12
++13
+`)
+	assert.Equal(Diff("a\n", "a"), `--- want
++++ got
+\ newline at end of file differs
+`)
+}
+
+func TestDiffWhitespace(t *testing.T) {
+	Assert{t}.Equal(Diff("a b\n", "a\tb \n"), `--- want
++++ got
+@@ -1 +1 @@
+-a b
++a→b·
+`)
+}
+
+func TestDiffLarge(t *testing.T) {
+	var want, got string
+	for i := 0; i < 4000; i++ {
+		want += fmt.Sprintln("want", i)
+		got += fmt.Sprintln("got", i)
+	}
+	d := Diff("same\n"+want+"same\n", "same\n"+got+"same\n")
+	assert := Assert{t}
+	assert.True(strings.HasPrefix(d, `--- want
++++ got
+@@ -1,4002 +1,4002 @@
+ same
+-want 0
+`))
+	assert.True(strings.HasSuffix(d, "+got 3999\n same\n"))
+}
+
+func TestDeepDiff(t *testing.T) {
+	type route struct {
+		Prefix string
+		GW     string
+	}
+	type netdev struct {
+		Ifname string
+		MTU    int
+		Routes []route
+		Flags  map[string]bool
+	}
+	assert := Assert{t}
+	want := netdev{
+		Ifname: "eth0",
+		MTU:    1500,
+		Routes: []route{{"10.1.0.0/24", "10.1.0.1"}},
+		Flags:  map[string]bool{"up": true},
+	}
+	assert.DeepEqual(want, want)
+	got := netdev{
+		Ifname: "eth0",
+		MTU:    9000,
+		Routes: []route{
+			{"10.1.0.0/24", "10.1.0.3"},
+			{"default", "10.1.0.1"},
+		},
+		Flags: map[string]bool{"lower_up": true},
+	}
+	assert.Equal(strings.Join(DeepDiff(want, got), "\n"), `.MTU: want 1500, got 9000
+.Routes[0].GW: want "10.1.0.1", got "10.1.0.3"
+.Routes[1]: extra {Prefix:default GW:10.1.0.1}
+.Flags["lower_up"]: extra true
+.Flags["up"]: missing true`)
+}
@@ -6,11 +6,13 @@ package docker
 
 import (
 	"context"
+	"encoding/binary"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"os"
 	"os/exec"
+	"regexp"
 	"strings"
 	"syscall"
 	"testing"
@@ -29,6 +31,12 @@ type Router struct {
 	Image    string
 	Hostname string // netns
 	Cmd      string
+	// Ready is the routing daemon's listener, "PROTO ADDR" as in
+	// test.Socket, e.g. "tcp *:2601" or "unix @/var/run/zserv.api".
+	Ready string
+	// ReadyLog is a regexp matching a container log line printed
+	// by the routing daemon once it's ready.
+	ReadyLog string
 	Intfs    []struct {
 		DevType  int
 		IsBridge bool
@@ -162,11 +170,16 @@ func LaunchContainers(t *testing.T, source []byte) (config *Config, err error) {
 			return
 		}
 		config.Routers[i].id = cresp.ID
-		// wait time for routing daemon before adding interfaces
-		time.Sleep(2 * time.Second)
+		router.id = cresp.ID
+		// wait for routing daemon before adding interfaces
+		err = waitContainerReady(config, router)
+		if err != nil {
+			err = fmt.Errorf("%v: %v", router.Hostname, err)
+			return
+		}
 
 		// set rp_filter off, need to do this again later per interface
-		lc.Program("ip", "netns", "exec", router.Hostname,
+		lc.Program(test.Netns(router.Hostname),
 			"sysctl", "-w", "net/ipv4/conf/all/rp_filter=0")
 
 		for _, intf := range router.Intfs {
@@ -195,21 +208,21 @@ func LaunchContainers(t *testing.T, source []byte) (config *Config, err error) {
 				lc.Program("ip", "link", "set", newIntf, "up")
 				intf.Name = newIntf
 			} else if intf.DevType == netport.NETPORT_DEVTYPE_BRIDGE {
-				lc.Program("ip", "netns", "exec", ns,
+				lc.Program(test.Netns(ns),
 					"ip", "link", "add", intf.Name, "type", "bridge")
-				lc.Program("ip", "netns", "exec", ns,
+				lc.Program(test.Netns(ns),
 					"ip", "addr", "add", intf.Address, "dev", intf.Name)
-				lc.Program("ip", "netns", "exec", ns,
+				lc.Program(test.Netns(ns),
 					"ip", "link", "set", intf.Name, "up")
 			}
 			if intf.DevType == netport.NETPORT_DEVTYPE_BRIDGE_PORT {
-				lc.Program("ip", "netns", "exec", ns,
+				lc.Program(test.Netns(ns),
 					"ip", "link", "set", intf.Name, "master", intf.Upper)
 			}
 			if intf.DevType != netport.NETPORT_DEVTYPE_BRIDGE {
 				moveIntfContainer(t, ns, intf.Name, intf.Address)
 			}
-			lc.Program("ip", "netns", "exec", ns,
+			lc.Program(test.Netns(ns),
 				"sysctl", "-w",
 				"net/ipv4/conf/"+intf.Name+"/rp_filter=0")
 
@@ -304,52 +317,56 @@ func PingCmd(t *testing.T, ID string, config *Config, target string) error {
 	cli := config.cli
 	ctx := context.Background()
 
-	count := 10
-	for i := 0; i < count; i++ {
-
+	var fatal error
+	try := 0
+	err := test.Eventually(func() (interface{}, bool) {
+		try++
 		execResp, err := cli.ContainerExecCreate(ctx, ID, execOpts)
 		if err != nil {
 			t.Logf("Error creating exec: %v", err)
-			return err
+			fatal = err
+			return err, true
 		}
 
 		hresp, err := cli.ContainerExecAttach(ctx, execResp.ID,
 			execOpts)
 		if err != nil {
 			t.Logf("Error attaching exec: %v", err)
-			return err
+			fatal = err
+			return err, true
 		}
 		defer hresp.Close()
 
 		content, err := ioutil.ReadAll(hresp.Reader)
 		if err != nil {
 			t.Logf("Error reading output: %v", err)
-			return err
+			fatal = err
+			return err, true
 		}
 		out := string(content)
 
 		ei, err := cli.ContainerExecInspect(ctx, execResp.ID)
 		if err != nil {
 			t.Logf("Error exec Inspect: %v", err)
-			return err
+			fatal = err
+			return err, true
 		}
 		if ei.Running {
-			t.Logf("exec still running", ei)
-			return err
+			t.Logf("exec still running %v", ei)
+			return out, true
 		}
 		if ei.ExitCode == 0 {
-			return nil
-		}
-		Commentf(t, "%v\nping count %v", out, i)
-		if ei.ExitCode != 0 {
-			Commentf(t, "[%v] exit code %v", cmd, ei.ExitCode)
+			return out, true
 		}
-		time.Sleep(1 * time.Second)
+		Commentf(t, "%v\nping count %v", out, try)
+		Commentf(t, "[%v] exit code %v", cmd, ei.ExitCode)
+		return out, false
+	}, 0, time.Second, test.Attempts(10))
+	if fatal != nil {
+		return fatal
 	}
-
-	err := fmt.Errorf("ping timeout %v -> %v", ID, target)
-	if err != nil && false {
-		test.Pause.Prompt("ping timeout ", ID, " -> ", target)
+	if err != nil {
+		err = fmt.Errorf("ping timeout %v -> %v: %v", ID, target, err)
 	}
 	return err
 }
@@ -377,10 +394,11 @@ func TearDownContainers(t *testing.T, config *Config) {
 		// delete bridge after members moved to default and deleted
 		for _, intf := range r.Intfs {
 			if intf.DevType == netport.NETPORT_DEVTYPE_BRIDGE {
-				td.Program("ip", "netns", "exec", r.Hostname,
+				td.Program(test.Netns(r.Hostname),
 					"ip", "link", "del", intf.Name)
 			}
 		}
+		saveContainerLog(t, config, r)
 		err := stopContainer(t, config, r.Hostname, r.id)
 		if err != nil {
 			t.Logf("Error: stopping %v: %v", r.Hostname, err)
@@ -395,6 +413,30 @@ func TearDownContainers(t *testing.T, config *Config) {
 	config.cli.Close()
 }
 
+// saveContainerLog as a test artifact with -test.artifacts.dir
+func saveContainerLog(t *testing.T, config *Config, r Router) {
+	f, err := test.Artifact(t, r.Hostname+".log")
+	if f == nil {
+		if err != nil {
+			t.Log(err)
+		}
+		return
+	}
+	defer f.Close()
+	rc, err := config.cli.ContainerLogs(context.Background(), r.id,
+		types.ContainerLogsOptions{
+			ShowStdout: true,
+			ShowStderr: true,
+			Timestamps: true,
+		})
+	if err != nil {
+		t.Log(err)
+		return
+	}
+	defer rc.Close()
+	io.Copy(f, rc)
+}
+
 func isImageLocal(t *testing.T, cli *client.Client, router Router) bool {
 
 	images, err := cli.ImageList(context.Background(),
@@ -433,6 +475,92 @@ func isContainerRunning(t *testing.T, config *Config, name string) bool {
 	return false
 }
 
+// waitContainerReady probes the router's Ready listener and ReadyLog; without
+// either, it just allows the routing daemon time to start.
+func waitContainerReady(config *Config, router Router) error {
+	probes := []test.Probe{func() error {
+		return isContainerReady(config, router.id, router.Hostname)
+	}}
+	if len(router.Ready) > 0 {
+		f := strings.Fields(router.Ready)
+		if len(f) != 2 {
+			return fmt.Errorf("ready: %q: not PROTO ADDR", router.Ready)
+		}
+		probes = append(probes, test.NetnsListener(
+			test.Netns(router.Hostname),
+			test.Socket{Proto: f[0], Addr: f[1]}))
+	}
+	if len(router.ReadyLog) > 0 {
+		re, err := regexp.Compile("(?m)" + router.ReadyLog)
+		if err != nil {
+			return fmt.Errorf("readylog: %v", err)
+		}
+		probes = append(probes, func() error {
+			return isContainerLogged(config, router.id, re)
+		})
+	}
+	err := test.WaitFor(test.ReadyTimeout, func() error {
+		for _, probe := range probes {
+			if err := probe(); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err == nil && len(probes) == 1 {
+		time.Sleep(2 * time.Second)
+	}
+	return err
+}
+
+func isContainerLogged(config *Config, ID string, re *regexp.Regexp) error {
+	rc, err := config.cli.ContainerLogs(context.Background(), ID,
+		types.ContainerLogsOptions{
+			ShowStdout: true,
+			ShowStderr: true,
+		})
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	b, err := ioutil.ReadAll(rc)
+	if err != nil {
+		return err
+	}
+	if !re.Match(demuxLog(b)) {
+		return fmt.Errorf("no log line matching %q", re)
+	}
+	return nil
+}
+
+// demuxLog strips the 8-byte stream headers from a non-tty container log.
+func demuxLog(b []byte) []byte {
+	var out []byte
+	for len(b) >= 8 && b[0] <= 2 && b[1] == 0 && b[2] == 0 && b[3] == 0 {
+		n := int(binary.BigEndian.Uint32(b[4:8]))
+		b = b[8:]
+		if n > len(b) {
+			n = len(b)
+		}
+		out = append(out, b[:n]...)
+		b = b[n:]
+	}
+	return append(out, b...)
+}
+
+func isContainerReady(config *Config, ID, name string) error {
+	info, err := config.cli.ContainerInspect(context.Background(), ID)
+	if err != nil {
+		return err
+	}
+	if info.ContainerJSONBase == nil || info.State == nil ||
+		!info.State.Running {
+		return fmt.Errorf("container not running")
+	}
+	_, err = os.Stat("/var/run/netns/" + name)
+	return err
+}
+
 func pullImage(t *testing.T, cli *client.Client, router Router) error {
 	repo := "docker.io/library/" + router.Image
 	out, err := cli.ImagePull(context.Background(), repo,
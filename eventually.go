@@ -0,0 +1,143 @@
+// Copyright © 2015-2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package test
+
+import (
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+// A Condition returns the value that it observed and whether that satisfies
+// the condition. The last observed value is shown on failure.
+type Condition func() (interface{}, bool)
+
+// Backoff option multiplies the poll period by Factor after each
+// observation, up to Max if non-zero.
+type Backoff struct {
+	Factor float64
+	Max    time.Duration
+}
+
+// Jitter option randomly varies each poll period by up to this fraction.
+type Jitter float64
+
+// Attempts option limits the number of observations.
+type Attempts int
+
+type poller struct {
+	period   time.Duration
+	backoff  Backoff
+	jitter   Jitter
+	attempts int
+}
+
+// newPoller returns an error for an unexpected option or, if it may sleep,
+// a period that isn't positive.
+func newPoller(period time.Duration, sleeps bool,
+	options ...interface{}) (*poller, error) {
+	p := &poller{period: period}
+	for _, option := range options {
+		switch t := option.(type) {
+		case Backoff:
+			p.backoff = t
+		case Jitter:
+			p.jitter = t
+		case Attempts:
+			p.attempts = int(t)
+		default:
+			return nil, fmt.Errorf("unexpected option: %T", t)
+		}
+	}
+	if sleeps && p.attempts != 1 && period <= 0 {
+		return nil, fmt.Errorf("invalid period: %v", period)
+	}
+	return p, nil
+}
+
+// sleep for the next period, but not beyond the deadline.
+func (p *poller) sleep(deadline time.Time) {
+	d := p.period
+	if p.jitter != 0 {
+		d += time.Duration(float64(d) * float64(p.jitter) *
+			(2*rand.Float64() - 1))
+	}
+	if left := time.Until(deadline); !deadline.IsZero() && d > left {
+		d = left
+	}
+	time.Sleep(d)
+	if p.backoff.Factor > 0 {
+		p.period = time.Duration(float64(p.period) * p.backoff.Factor)
+		if p.backoff.Max > 0 && p.period > p.backoff.Max {
+			p.period = p.backoff.Max
+		}
+	}
+}
+
+// Eventually polls the condition every period until it's satisfied or the
+// timeout expires; a zero timeout polls until the Attempts option is spent.
+// Options are Backoff, Jitter, and Attempts.
+func Eventually(cond Condition, timeout, period time.Duration,
+	options ...interface{}) error {
+	p, err := newPoller(period, timeout > 0, options...)
+	if err != nil {
+		return err
+	}
+	var deadline time.Time
+	if timeout > 0 {
+		deadline = time.Now().Add(timeout)
+	}
+	for try := 1; ; try++ {
+		v, ok := cond()
+		if ok {
+			return nil
+		}
+		if (!deadline.IsZero() && !time.Now().Before(deadline)) ||
+			(p.attempts > 0 && try >= p.attempts) ||
+			(deadline.IsZero() && p.attempts == 0) {
+			return fmt.Errorf("unsatisfied after %d tries: %v",
+				try, v)
+		}
+		p.sleep(deadline)
+	}
+}
+
+// Consistently polls the condition every period for the given duration and
+// returns an error at the first observation that isn't satisfied. Options
+// are Backoff and Jitter.
+func Consistently(cond Condition, duration, period time.Duration,
+	options ...interface{}) error {
+	p, err := newPoller(period, duration > 0, options...)
+	if err != nil {
+		return err
+	}
+	start := time.Now()
+	deadline := start.Add(duration)
+	for {
+		v, ok := cond()
+		if !ok {
+			return fmt.Errorf("unsatisfied after %v: %v",
+				time.Since(start).Round(time.Millisecond), v)
+		}
+		if !time.Now().Before(deadline) {
+			return nil
+		}
+		p.sleep(deadline)
+	}
+}
+
+// Eventually asserts that the condition is satisfied w/in timeout.
+func (assert Assert) Eventually(cond Condition, timeout, period time.Duration,
+	options ...interface{}) {
+	assert.Helper()
+	assert.Nil(Eventually(cond, timeout, period, options...))
+}
+
+// Consistently asserts that the condition remains satisfied for duration.
+func (assert Assert) Consistently(cond Condition, duration,
+	period time.Duration, options ...interface{}) {
+	assert.Helper()
+	assert.Nil(Consistently(cond, duration, period, options...))
+}
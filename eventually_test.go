@@ -0,0 +1,48 @@
+// Copyright © 2015-2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package test
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEventually(t *testing.T) {
+	assert := Assert{t}
+	n := 0
+	count := func() (interface{}, bool) {
+		n++
+		return n, n == 3
+	}
+	assert.Eventually(count, time.Second, time.Millisecond,
+		Backoff{Factor: 2, Max: 10 * time.Millisecond}, Jitter(0.1))
+	assert.Equal(Eventually(count, 0, time.Millisecond, Attempts(2)).Error(),
+		"unsatisfied after 2 tries: 5")
+	assert.Error(Eventually(count, 50*time.Millisecond, 0),
+		"invalid period: 0s")
+	assert.Error(Consistently(count, time.Second, -time.Millisecond),
+		"invalid period: -1ms")
+	assert.Error(Eventually(count, time.Second, time.Millisecond, "x"),
+		"unexpected option: string")
+	assert.Nil(Eventually(func() (interface{}, bool) {
+		return nil, true
+	}, time.Second, 0, Attempts(1)))
+}
+
+func TestConsistently(t *testing.T) {
+	assert := Assert{t}
+	n := 0
+	assert.Consistently(func() (interface{}, bool) {
+		n++
+		return n, true
+	}, 20*time.Millisecond, 5*time.Millisecond)
+	if n < 2 {
+		t.Fatal("observed", n, "times")
+	}
+	err := Consistently(func() (interface{}, bool) {
+		return "reachable", false
+	}, time.Second, time.Millisecond)
+	assert.Error(err, "unsatisfied after 0s: reachable")
+}
@@ -14,6 +14,27 @@ var (
 		"don't run, just print test names")
 	VV  = flag.Bool("test.vv", false, "log program output")
 	VVV = flag.Bool("test.vvv", false, "log program execution")
+
+	StreamFlag = flag.Bool("test.stream", false,
+		"log program and daemon output as it's received")
+
+	ArtifactsFlag = flag.String("test.artifacts.dir", "",
+		"save program transcripts, logs and captures in this directory")
+	PruneArtifactsFlag = flag.Bool("test.artifacts.prune", false,
+		"remove the artifacts of passing tests")
+
+	RecordFlag = flag.Bool("test.record", false,
+		"record program transcripts in "+ReplayDir)
+	ReplayFlag = flag.Bool("test.replay", false,
+		"replay program transcripts from "+ReplayDir+
+			" instead of running programs")
+
+	UpdateFlag = flag.Bool("test.update", false,
+		"rewrite the "+GoldenDir+" files of Assert.Golden")
+
+	PingFlag = flag.String("test.ping", "program",
+		"send Ping requests with the ping program (program) or from "+
+			"a raw socket (native)")
 )
 
 func SkipIfDryRun(t *testing.T) {
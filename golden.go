@@ -0,0 +1,41 @@
+// Copyright © 2015-2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// GoldenDir has the expected output files of Assert.Golden.
+const GoldenDir = "testdata/golden"
+
+// Golden asserts that got is the same as the content of the named file in
+// GoldenDir/TEST_NAME; or, with -test.update, rewrites the file with got.
+func (assert Assert) Golden(name, got string) {
+	assert.Helper()
+	rel := filepath.Join(GoldenDir, filepath.FromSlash(assert.Name()),
+		name)
+	fn := filepath.Join(packageDir, rel)
+	if *UpdateFlag {
+		assert.Nil(os.MkdirAll(filepath.Dir(fn), 0755))
+		assert.Nil(ioutil.WriteFile(fn, []byte(got), 0644))
+		return
+	}
+	b, err := ioutil.ReadFile(fn)
+	if os.IsNotExist(err) {
+		assert.Fatalf("%s: missing; use -test.update to create", rel)
+		return
+	}
+	if err != nil {
+		assert.Fatal(err)
+		return
+	}
+	if diff := Diff(string(b), got); len(diff) > 0 {
+		assert.Fatalf("%s: mismatch; use -test.update to accept\n%s",
+			rel, diff)
+	}
+}
@@ -0,0 +1,11 @@
+// Copyright © 2015-2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package test
+
+import "testing"
+
+func TestGolden(t *testing.T) {
+	Assert{t}.Golden("lines", "one\ntwo\n")
+}
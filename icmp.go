@@ -0,0 +1,273 @@
+// Copyright © 2015-2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package test
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"math/rand"
+	"net"
+	"os"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// A Pinger sends the echo requests of Ping. A Pinger given as an option of
+// Ping or Assert.Ping is used instead of that selected by -test.ping.
+type Pinger interface {
+	Ping(netns Netns, addr string, options ...interface{}) (*PingStats,
+		error)
+}
+
+// ProgramPinger runs the ping program within the namespace; this is the
+// default.
+type ProgramPinger struct{}
+
+// NativePinger sends ICMP or ICMPv6 echo requests from a raw socket opened
+// within the namespace so that no program is run; this needs CAP_NET_RAW.
+// Select it with -test.ping=native or as an option.
+type NativePinger struct{}
+
+func (ProgramPinger) Ping(netns Netns, addr string,
+	options ...interface{}) (*PingStats, error) {
+	po, err := newPingOptions(options)
+	if err != nil {
+		return nil, err
+	}
+	return po.program(netns, addr)
+}
+
+func (NativePinger) Ping(netns Netns, addr string,
+	options ...interface{}) (*PingStats, error) {
+	po, err := newPingOptions(options)
+	if err != nil {
+		return nil, err
+	}
+	return po.native(netns, addr)
+}
+
+// pinger is the default selected by -test.ping.
+func pinger() Pinger {
+	if *PingFlag == "native" {
+		return NativePinger{}
+	}
+	return ProgramPinger{}
+}
+
+const (
+	icmpEchoRequest   = 8
+	icmpEchoReply     = 0
+	icmpv6EchoRequest = 128
+	icmpv6EchoReply   = 129
+
+	// the time to wait for each reply, like ping -W 1
+	replyTimeout = time.Second
+)
+
+// native sends the echo requests from a raw socket; if it can't be opened
+// for lack of privilege, this falls back to the ping program.
+func (po *pingOptions) native(netns Netns, addr string) (*PingStats,
+	error) {
+	dst, err := net.ResolveIPAddr("ip", addr)
+	if err != nil {
+		return nil, err
+	}
+	v6 := dst.IP.To4() == nil
+	conn, err := po.listen(netns, v6)
+	if os.IsPermission(err) {
+		return po.program(netns, addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	id := uint16(rand.Intn(1 << 16))
+	interval := time.Second
+	if po.interval > 0 {
+		interval = time.Duration(po.interval)
+	}
+	stats := new(PingStats)
+	for seq := 1; seq <= int(po.count); seq++ {
+		if seq > 1 {
+			time.Sleep(interval)
+		}
+		start := time.Now()
+		_, err = conn.WriteTo(echoRequest(v6, id, uint16(seq),
+			int(po.size)), dst)
+		if err != nil {
+			if stats.Transmitted == 0 {
+				return nil, err
+			}
+			continue
+		}
+		stats.Transmitted++
+		if awaitReply(conn, v6, id, uint16(seq), start) {
+			stats.Received++
+			stats.RTTs = append(stats.RTTs, time.Since(start))
+		}
+	}
+	stats.summarize()
+	return stats, po.check(stats)
+}
+
+// listen opens a raw ICMP socket within the namespace; an interface
+// PingSource binds the socket to that device, otherwise to that address.
+func (po *pingOptions) listen(netns Netns, v6 bool) (*net.IPConn, error) {
+	var conn *net.IPConn
+	err := netns.Do(func() error {
+		network, laddr := "ip4:icmp", &net.IPAddr{}
+		if v6 {
+			network = "ip6:ipv6-icmp"
+		}
+		src := string(po.source)
+		if ip := net.ParseIP(strings.Split(src, "%")[0]); ip != nil {
+			a, err := net.ResolveIPAddr("ip", src)
+			if err != nil {
+				return err
+			}
+			laddr, src = a, ""
+		}
+		var err error
+		conn, err = net.ListenIP(network, laddr)
+		if err != nil {
+			return err
+		}
+		if err = po.setsockopts(conn, v6, src); err != nil {
+			conn.Close()
+		}
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, syscall.EPERM) || errors.Is(err, syscall.EACCES) {
+			return nil, os.ErrPermission
+		}
+		return nil, err
+	}
+	return conn, nil
+}
+
+func (po *pingOptions) setsockopts(conn *net.IPConn, v6 bool,
+	ifname string) error {
+	rc, err := conn.SyscallConn()
+	if err != nil {
+		return err
+	}
+	var serr error
+	err = rc.Control(func(fd uintptr) {
+		if len(ifname) > 0 {
+			serr = syscall.SetsockoptString(int(fd),
+				syscall.SOL_SOCKET, syscall.SO_BINDTODEVICE,
+				ifname)
+		}
+		if serr == nil && po.dontFragment {
+			if v6 {
+				serr = syscall.SetsockoptInt(int(fd),
+					syscall.IPPROTO_IPV6,
+					syscall.IPV6_MTU_DISCOVER,
+					syscall.IPV6_PMTUDISC_DO)
+			} else {
+				serr = syscall.SetsockoptInt(int(fd),
+					syscall.IPPROTO_IP,
+					syscall.IP_MTU_DISCOVER,
+					syscall.IP_PMTUDISC_DO)
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+	return serr
+}
+
+// echoRequest returns an ICMP message with size bytes of payload (56 by
+// default, like ping). The kernel sums ICMPv6.
+func echoRequest(v6 bool, id, seq uint16, size int) []byte {
+	if size <= 0 {
+		size = 56
+	}
+	b := make([]byte, 8+size)
+	b[0] = icmpEchoRequest
+	if v6 {
+		b[0] = icmpv6EchoRequest
+	}
+	binary.BigEndian.PutUint16(b[4:], id)
+	binary.BigEndian.PutUint16(b[6:], seq)
+	for i := range b[8:] {
+		b[8+i] = byte(i)
+	}
+	if !v6 {
+		binary.BigEndian.PutUint16(b[2:], checksum(b))
+	}
+	return b
+}
+
+func checksum(b []byte) uint16 {
+	var sum uint32
+	for i := 0; i+1 < len(b); i += 2 {
+		sum += uint32(b[i])<<8 | uint32(b[i+1])
+	}
+	if len(b)%2 == 1 {
+		sum += uint32(b[len(b)-1]) << 8
+	}
+	for sum > 0xffff {
+		sum = sum>>16 + sum&0xffff
+	}
+	return ^uint16(sum)
+}
+
+// awaitReply is true if the matching echo reply is received w/in the
+// replyTimeout; raw sockets receive all ICMP messages, including the
+// requests of local destinations and those of other probes.
+func awaitReply(conn *net.IPConn, v6 bool, id, seq uint16,
+	start time.Time) bool {
+	reply := byte(icmpEchoReply)
+	if v6 {
+		reply = icmpv6EchoReply
+	}
+	conn.SetReadDeadline(start.Add(replyTimeout))
+	b := make([]byte, 1<<16)
+	for {
+		n, _, err := conn.ReadFrom(b)
+		if err != nil {
+			return false
+		}
+		if n >= 8 && b[0] == reply &&
+			binary.BigEndian.Uint16(b[4:]) == id &&
+			binary.BigEndian.Uint16(b[6:]) == seq {
+			return true
+		}
+	}
+}
+
+// summarize the loss and RTTs of the received replies.
+func (stats *PingStats) summarize() {
+	if stats.Transmitted > 0 {
+		stats.Loss = 100 * float64(stats.Transmitted-stats.Received) /
+			float64(stats.Transmitted)
+	}
+	if len(stats.RTTs) == 0 {
+		return
+	}
+	var sum, sumsq float64
+	stats.Min = stats.RTTs[0]
+	for _, rtt := range stats.RTTs {
+		if rtt < stats.Min {
+			stats.Min = rtt
+		}
+		if rtt > stats.Max {
+			stats.Max = rtt
+		}
+		sum += float64(rtt)
+		sumsq += float64(rtt) * float64(rtt)
+	}
+	n := float64(len(stats.RTTs))
+	avg := sum / n
+	stats.Avg = time.Duration(avg)
+	if v := sumsq/n - avg*avg; v > 0 {
+		stats.Mdev = time.Duration(math.Sqrt(v))
+	}
+}
@@ -0,0 +1,37 @@
+// Copyright © 2015-2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// IP runs iproute2 "ip -j ARGS..." within the network namespace and decodes
+// its JSON output into v.
+func IP(netns Netns, v interface{}, args ...string) error {
+	cmd := exec.Command("ip", append([]string{"-j"}, args...)...)
+	if *VVV {
+		Log().Output(2, fmt.Sprint(netns, " ", cmd.Args))
+	}
+	b, err := netns.Output(cmd)
+	if ee, ok := err.(*exec.ExitError); ok && len(ee.Stderr) > 0 {
+		return fmt.Errorf("%v: %s", cmd.Args,
+			strings.TrimSpace(string(ee.Stderr)))
+	}
+	if err != nil {
+		return fmt.Errorf("%v: %v", cmd.Args, err)
+	}
+	if len(strings.TrimSpace(string(b))) == 0 {
+		// older iproute2 prints nothing rather than []
+		return nil
+	}
+	if err = json.Unmarshal(b, v); err != nil {
+		return fmt.Errorf("%v: %v", cmd.Args, err)
+	}
+	return nil
+}
@@ -0,0 +1,259 @@
+// Copyright © 2015-2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package test
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// A Link is a network interface of a namespace as shown by "ip -j -d link".
+// As the expectation of Assert.LinkUp, only its non-zero fields are
+// compared.
+type Link struct {
+	Name      string
+	Index     int
+	Operstate string
+	Up        bool // administratively
+	Carrier   bool
+	MTU       int
+	MAC       string
+	Master    string
+	Parent    string // the lower link of a vlan, macvlan, veth, etc.
+	Kind      string // e.g. "vlan", "bridge", or "" if physical
+	VlanID    int
+}
+
+// An Addr is a network address of an interface as shown by "ip -j addr".
+type Addr struct {
+	Ifname    string
+	Family    string // "inet" or "inet6"
+	Local     string
+	Prefixlen int
+	Scope     string
+}
+
+func (a Addr) String() string {
+	return fmt.Sprint(a.Local, "/", a.Prefixlen)
+}
+
+// ipLink is the JSON of "ip -j -d link" and "ip -j addr".
+type ipLink struct {
+	Ifindex   int      `json:"ifindex"`
+	Ifname    string   `json:"ifname"`
+	Flags     []string `json:"flags"`
+	MTU       int      `json:"mtu"`
+	Operstate string   `json:"operstate"`
+	Address   string   `json:"address"`
+	Master    string   `json:"master"`
+	Link      string   `json:"link"`
+	Linkinfo  struct {
+		InfoKind string `json:"info_kind"`
+		InfoData struct {
+			ID int `json:"id"`
+		} `json:"info_data"`
+	} `json:"linkinfo"`
+	AddrInfo []struct {
+		Family    string `json:"family"`
+		Local     string `json:"local"`
+		Prefixlen int    `json:"prefixlen"`
+		Scope     string `json:"scope"`
+	} `json:"addr_info"`
+}
+
+func (l *ipLink) flag(name string) bool {
+	for _, flag := range l.Flags {
+		if flag == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Links returns the network interfaces of the namespace.
+func Links(netns Netns) ([]Link, error) {
+	var ipLinks []ipLink
+	if err := IP(netns, &ipLinks, "-d", "link", "show"); err != nil {
+		return nil, err
+	}
+	links := make([]Link, len(ipLinks))
+	for i, l := range ipLinks {
+		links[i] = Link{
+			Name:      l.Ifname,
+			Index:     l.Ifindex,
+			Operstate: l.Operstate,
+			Up:        l.flag("UP"),
+			Carrier:   l.flag("LOWER_UP"),
+			MTU:       l.MTU,
+			MAC:       l.Address,
+			Master:    l.Master,
+			Parent:    l.Link,
+			Kind:      l.Linkinfo.InfoKind,
+		}
+		if links[i].Kind == "vlan" {
+			links[i].VlanID = l.Linkinfo.InfoData.ID
+		}
+	}
+	return links, nil
+}
+
+// Addrs returns the network addresses of the named interface; or, if
+// ifname is empty, of all interfaces in the namespace.
+func Addrs(netns Netns, ifname string) ([]Addr, error) {
+	args := []string{"addr", "show"}
+	if len(ifname) > 0 {
+		args = append(args, "dev", ifname)
+	}
+	var ipLinks []ipLink
+	if err := IP(netns, &ipLinks, args...); err != nil {
+		return nil, err
+	}
+	var addrs []Addr
+	for _, l := range ipLinks {
+		for _, a := range l.AddrInfo {
+			addrs = append(addrs, Addr{
+				Ifname:    l.Ifname,
+				Family:    a.Family,
+				Local:     a.Local,
+				Prefixlen: a.Prefixlen,
+				Scope:     a.Scope,
+			})
+		}
+	}
+	return addrs, nil
+}
+
+// Match is true if l has the non-zero fields of want.
+func (l Link) Match(want Link) bool {
+	for _, f := range []struct{ got, want string }{
+		{l.Name, want.Name},
+		{l.Operstate, want.Operstate},
+		{l.Master, want.Master},
+		{l.Parent, want.Parent},
+		{l.Kind, want.Kind},
+	} {
+		if len(f.want) > 0 && f.got != f.want {
+			return false
+		}
+	}
+	return (want.Index == 0 || l.Index == want.Index) &&
+		(!want.Up || l.Up) &&
+		(!want.Carrier || l.Carrier) &&
+		(want.MTU == 0 || l.MTU == want.MTU) &&
+		(len(want.MAC) == 0 || strings.EqualFold(l.MAC, want.MAC)) &&
+		(want.VlanID == 0 || l.VlanID == want.VlanID)
+}
+
+// link polls the namespace for the named interface until it matches want
+// or the wait expires; it returns the last one found.
+func link(netns Netns, ifname string, want Link,
+	wait time.Duration) (Link, error) {
+	var found Link
+	var err error
+	cond := func() (interface{}, bool) {
+		var links []Link
+		found = Link{}
+		if links, err = Links(netns); err != nil {
+			return err, true
+		}
+		for _, l := range links {
+			if l.Name == ifname {
+				found = l
+				return l, l.Match(want)
+			}
+		}
+		err = fmt.Errorf("%s: %s not found", netns, ifname)
+		return err, false
+	}
+	if wait > 0 {
+		Eventually(cond, wait, ProbePeriod)
+	} else {
+		cond()
+	}
+	if err == nil && !found.Match(want) {
+		err = fmt.Errorf("%s: %s is %+v, not %+v", netns, ifname,
+			found, want)
+	}
+	return found, err
+}
+
+// LinkUp asserts that the named interface of the namespace is
+// administratively up, with options:
+//
+//	Link
+//		the interface must also match these non-zero fields, e.g.
+//		Carrier, Kind, or VlanID
+//	time.Duration
+//		wait up to this long for the interface to match
+//
+// It returns the interface.
+// Usage:
+//
+//	assert.LinkUp("h1", "eth1.10", Link{Kind: "vlan", VlanID: 10,
+//		Carrier: true}, 3*time.Second)
+func (assert Assert) LinkUp(netns, ifname string,
+	options ...interface{}) Link {
+	assert.Helper()
+	var want Link
+	var wait time.Duration
+	for _, option := range options {
+		switch t := option.(type) {
+		case Link:
+			want = t
+		case time.Duration:
+			wait = t
+		default:
+			assert.Fatalf("unexpected option: %T", t)
+			return Link{}
+		}
+	}
+	want.Name, want.Up = ifname, true
+	l, err := link(Netns(netns), ifname, want, wait)
+	if err != nil {
+		assert.Fatal(err)
+	}
+	return l
+}
+
+// Master asserts that the named interface of the namespace is enslaved to
+// master, e.g. a bridge.
+func (assert Assert) Master(netns, ifname, master string) {
+	assert.Helper()
+	_, err := link(Netns(netns), ifname, Link{Master: master}, 0)
+	if err != nil {
+		assert.Fatal(err)
+	}
+}
+
+// Addr asserts that the named interface of the namespace has the address,
+// given as "ADDRESS/PREFIXLEN" or, for any prefix length, "ADDRESS"; it
+// returns the address.
+func (assert Assert) Addr(netns, ifname, addr string) Addr {
+	assert.Helper()
+	addrs, err := Addrs(Netns(netns), ifname)
+	if err != nil {
+		assert.Fatal(err)
+		return Addr{}
+	}
+	ip, prefixlen := addr, -1
+	if i := strings.IndexByte(addr, '/'); i >= 0 {
+		ip = addr[:i]
+		fmt.Sscan(addr[i+1:], &prefixlen)
+	}
+	for _, a := range addrs {
+		if sameIP(a.Local, ip) &&
+			(prefixlen < 0 || a.Prefixlen == prefixlen) {
+			return a
+		}
+	}
+	have := make([]string, len(addrs))
+	for i, a := range addrs {
+		have[i] = a.String()
+	}
+	assert.Fatalf("%s: %s doesn't have %s; has %v", Netns(netns), ifname,
+		addr, have)
+	return Addr{}
+}
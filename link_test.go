@@ -0,0 +1,38 @@
+// Copyright © 2015-2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package test
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLink(t *testing.T) {
+	ns := testNetns(t)
+	assert := Assert{t}
+	assert.Program("ip", "-n", ns, "link", "add", "br0", "type", "bridge")
+	assert.Program("ip", "-n", ns, "link", "set", "br0", "up")
+	assert.Program("ip", "-n", ns, "link", "set", "d1", "master", "br0")
+
+	d0 := assert.LinkUp(ns, "d0", Link{Kind: "veth", Carrier: true,
+		MTU: 1500}, time.Second)
+	assert.Equal(d0.Parent, "d1")
+	assert.LinkUp(ns, "br0", Link{Kind: "bridge"})
+	assert.Master(ns, "d1", "br0")
+	links, err := Links(Netns(ns))
+	assert.Nil(err)
+	assert.Equal(links[0].Name, "lo")
+	assert.False(links[0].Up)
+
+	assert.Equal(assert.Addr(ns, "d0", "10.9.0.1/24").Family, "inet")
+	assert.Addr(ns, "d0", "10.9.0.1")
+	addrs, err := Addrs(Netns(ns), "")
+	assert.Nil(err)
+	found := false
+	for _, a := range addrs {
+		found = found || (a.Ifname == "d0" && a.String() == "10.9.0.1/24")
+	}
+	assert.True(found)
+}
@@ -0,0 +1,270 @@
+// Copyright © 2015-2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package test
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A Socket is a listener found in the /proc/net tables of a namespace.
+type Socket struct {
+	// Proto is "tcp4", "tcp6", "udp4", "udp6", or "unix"; to match, "tcp"
+	// and "udp" are either IP version and "" is any protocol.
+	Proto string
+	// Addr is "HOST:PORT" or, for unix, the socket path with a leading
+	// '@' if abstract. To match, an empty or "*" HOST is any address and
+	// a unix Addr may be a substring of the path.
+	Addr  string
+	Inode uint64
+}
+
+func (sock Socket) String() string {
+	return sock.Proto + " " + sock.Addr
+}
+
+// Owner option of Listener and NoListener is the process that must hold
+// the socket.
+type Owner int
+
+// Listeners returns the listening sockets of the network namespace: TCP in
+// LISTEN state, unconnected UDP, and bound unix sockets.
+func Listeners(netns Netns) ([]Socket, error) {
+	var socks []Socket
+	err := netns.Do(func() error {
+		// /proc/net is that of the thread group leader's namespace
+		const dir = "/proc/thread-self/net"
+		for _, proto := range []string{"tcp", "tcp6", "udp", "udp6"} {
+			err := scanNet(filepath.Join(dir, proto),
+				func(fields []string) {
+					if sock, ok := ipSocket(proto,
+						fields); ok {
+						socks = append(socks, sock)
+					}
+				})
+			if err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+		return scanNet(filepath.Join(dir, "unix"), func(fields []string) {
+			if len(fields) < 8 {
+				return
+			}
+			inode, _ := strconv.ParseUint(fields[6], 10, 64)
+			socks = append(socks, Socket{"unix", fields[7], inode})
+		})
+	})
+	return socks, err
+}
+
+// scanNet calls f with the fields of each entry of a /proc/net table.
+func scanNet(fn string, f func(fields []string)) error {
+	file, err := os.Open(fn)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for first := true; scanner.Scan(); first = false {
+		if !first {
+			f(strings.Fields(scanner.Text()))
+		}
+	}
+	return scanner.Err()
+}
+
+func ipSocket(proto string, fields []string) (Socket, bool) {
+	const tcpListen = "0A"
+	if len(fields) < 10 {
+		return Socket{}, false
+	}
+	if strings.HasPrefix(proto, "tcp") && fields[3] != tcpListen {
+		return Socket{}, false
+	}
+	if strings.HasPrefix(proto, "udp") &&
+		!strings.HasSuffix(fields[2], ":0000") {
+		return Socket{}, false
+	}
+	addr, ok := hexAddr(fields[1])
+	if !ok {
+		return Socket{}, false
+	}
+	if !strings.HasSuffix(proto, "6") {
+		proto += "4"
+	}
+	inode, _ := strconv.ParseUint(fields[9], 10, 64)
+	return Socket{proto, addr, inode}, true
+}
+
+// hexAddr converts a /proc/net address, IP words in host order and port,
+// like "0100007F:0016" to "127.0.0.1:22".
+func hexAddr(s string) (string, bool) {
+	i := strings.IndexByte(s, ':')
+	if i < 0 {
+		return "", false
+	}
+	b, err := hex.DecodeString(s[:i])
+	if err != nil || (len(b) != net.IPv4len && len(b) != net.IPv6len) {
+		return "", false
+	}
+	for w := 0; w < len(b); w += 4 {
+		b[w], b[w+1], b[w+2], b[w+3] = b[w+3], b[w+2], b[w+1], b[w]
+	}
+	port, err := strconv.ParseUint(s[i+1:], 16, 16)
+	if err != nil {
+		return "", false
+	}
+	return net.JoinHostPort(net.IP(b).String(), fmt.Sprint(port)), true
+}
+
+// Match is true if sock, a listener, satisfies the pattern; a listener on
+// the unspecified address matches any HOST of the same port.
+func (sock Socket) Match(pattern Socket) bool {
+	if !strings.HasPrefix(sock.Proto, pattern.Proto) {
+		return false
+	}
+	if sock.Proto == "unix" {
+		return strings.Contains(sock.Addr, pattern.Addr)
+	}
+	host, port, err := net.SplitHostPort(pattern.Addr)
+	if err != nil {
+		return false
+	}
+	shost, sport, _ := net.SplitHostPort(sock.Addr)
+	if port != sport {
+		return false
+	}
+	if len(host) == 0 || host == "*" {
+		return true
+	}
+	sip := net.ParseIP(shost)
+	return sip.IsUnspecified() || sip.Equal(net.ParseIP(host))
+}
+
+// owns is true if the process has an open file of the socket.
+func owns(pid int, inode uint64) bool {
+	dir := fmt.Sprint("/proc/", pid, "/fd")
+	fds, err := filepath.Glob(filepath.Join(dir, "*"))
+	if err != nil {
+		return false
+	}
+	want := fmt.Sprint("socket:[", inode, "]")
+	for _, fd := range fds {
+		if link, err := os.Readlink(fd); err == nil && link == want {
+			return true
+		}
+	}
+	return false
+}
+
+// listening returns the listener matching pattern or an error if there's
+// none.
+func listening(pattern Socket, netns Netns, owner Owner) (Socket, error) {
+	socks, err := Listeners(netns)
+	if err != nil {
+		return Socket{}, err
+	}
+	for _, sock := range socks {
+		if sock.Match(pattern) &&
+			(owner == 0 || owns(int(owner), sock.Inode)) {
+			return sock, nil
+		}
+	}
+	if owner != 0 {
+		return Socket{}, fmt.Errorf("no listener on %v owned by %d",
+			pattern, owner)
+	}
+	return Socket{}, fmt.Errorf("no listener on %v", pattern)
+}
+
+type listenerOptions struct {
+	pattern Socket
+	netns   Netns
+	owner   Owner
+	wait    time.Duration
+}
+
+func newListenerOptions(assert Assert, v interface{},
+	options []interface{}) listenerOptions {
+	assert.Helper()
+	var lo listenerOptions
+	switch t := v.(type) {
+	case Socket:
+		lo.pattern = t
+	case string:
+		lo.pattern = Socket{Proto: "unix", Addr: t}
+	default:
+		assert.Fatalf("unexpected socket: %T", t)
+	}
+	for _, option := range options {
+		switch t := option.(type) {
+		case Netns:
+			lo.netns = t
+		case Owner:
+			lo.owner = t
+		case time.Duration:
+			lo.wait = t
+		default:
+			assert.Fatalf("unexpected option: %T", t)
+		}
+	}
+	return lo
+}
+
+// poll the condition once or until the wait expires.
+func (lo listenerOptions) poll(cond Condition) error {
+	if lo.wait == 0 {
+		return Eventually(cond, 0, 0, Attempts(1))
+	}
+	return Eventually(cond, lo.wait, ProbePeriod)
+}
+
+// Listener asserts that there is a listener on the socket given as a Socket
+// pattern or the name of a Unix socket. Options:
+//
+//	Netns
+//		look in this network namespace instead of the test's
+//	Owner
+//		the socket must be held by this process
+//	time.Duration
+//		wait up to this long for the listener
+//
+// Usage:
+//
+//	assert.Listener(Socket{Proto: "tcp", Addr: ":179"}, Netns("R1"),
+//		Owner(p.Pid()), 10*time.Second)
+func (assert Assert) Listener(v interface{}, options ...interface{}) {
+	assert.Helper()
+	lo := newListenerOptions(assert, v, options)
+	err := lo.poll(func() (interface{}, bool) {
+		_, err := listening(lo.pattern, lo.netns, lo.owner)
+		return err, err == nil
+	})
+	if err != nil {
+		assert.Fatal(err)
+	}
+}
+
+// NoListener asserts that there isn't a listener on the socket; with a
+// time.Duration, it waits up to that long for the socket to be released.
+// The socket and options are those of Listener.
+func (assert Assert) NoListener(v interface{}, options ...interface{}) {
+	assert.Helper()
+	lo := newListenerOptions(assert, v, options)
+	err := lo.poll(func() (interface{}, bool) {
+		sock, err := listening(lo.pattern, lo.netns, lo.owner)
+		return sock, err != nil
+	})
+	if err != nil {
+		assert.Fatalf("%v in use: %v", lo.pattern, err)
+	}
+}
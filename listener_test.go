@@ -0,0 +1,54 @@
+// Copyright © 2015-2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package test
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestHexAddr(t *testing.T) {
+	assert := Assert{t}
+	for s, want := range map[string]string{
+		"0100007F:0016":                         "127.0.0.1:22",
+		"00000000000000000000000001000000:00B3": "[::1]:179",
+	} {
+		got, ok := hexAddr(s)
+		assert.True(ok)
+		assert.Equal(got, want)
+	}
+}
+
+func TestListener(t *testing.T) {
+	assert := Assert{t}
+	ln, err := net.Listen("tcp4", "127.0.0.1:0")
+	assert.Nil(err)
+	tcp := Socket{Proto: "tcp", Addr: ln.Addr().String()}
+	assert.Listener(tcp, Owner(os.Getpid()))
+	_, port, _ := net.SplitHostPort(tcp.Addr)
+	assert.Listener(Socket{Proto: "tcp4", Addr: ":" + port})
+	assert.NoListener(Socket{Proto: "udp", Addr: ":" + port})
+	assert.NoListener(Socket{Proto: "tcp6", Addr: ":" + port})
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		ln.Close()
+	}()
+	assert.NoListener(tcp, time.Second)
+
+	pc, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	assert.Nil(err)
+	defer pc.Close()
+	assert.Listener(Socket{Proto: "udp", Addr: pc.LocalAddr().String()})
+
+	name := fmt.Sprint("@test-listener-", os.Getpid())
+	unix, err := net.Listen("unix", name)
+	assert.Nil(err)
+	assert.Listener(name, Owner(os.Getpid()))
+	unix.Close()
+	assert.NoListener(name)
+}
@@ -0,0 +1,178 @@
+// Copyright © 2015-2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// A Matcher is an expectation of program output or error text; a compiled
+// *regexp.Regexp is a Matcher. A Matcher given to Begin is matched with
+// Stdout.
+// Usage:
+//
+//	assert.Program(AllOf(Contains("UP"), Not(Contains("NO-CARRIER"))),
+//		"ip", "link", "show", "dev", ifname)
+type Matcher interface {
+	MatchString(s string) bool
+	String() string
+}
+
+type matcher struct {
+	desc  string
+	match func(string) bool
+}
+
+func (m matcher) MatchString(s string) bool { return m.match(s) }
+func (m matcher) String() string            { return m.desc }
+
+// Contains matches text with the given substring.
+func Contains(substr string) Matcher {
+	return matcher{fmt.Sprintf("contains %q", substr),
+		func(s string) bool { return strings.Contains(s, substr) }}
+}
+
+// Regexp matches text with the regex pattern; it panics if the pattern
+// doesn't compile.
+func Regexp(pattern string) Matcher {
+	return regexp.MustCompile(pattern)
+}
+
+// Not matches text that m doesn't.
+func Not(m Matcher) Matcher {
+	return matcher{fmt.Sprintf("not(%v)", m),
+		func(s string) bool { return !m.MatchString(s) }}
+}
+
+// AllOf matches text that every given Matcher does.
+func AllOf(ms ...Matcher) Matcher {
+	return matcher{"all(" + describe(ms) + ")", func(s string) bool {
+		for _, m := range ms {
+			if !m.MatchString(s) {
+				return false
+			}
+		}
+		return true
+	}}
+}
+
+// AnyOf matches text that at least one of the given Matchers does.
+func AnyOf(ms ...Matcher) Matcher {
+	return matcher{"any(" + describe(ms) + ")", func(s string) bool {
+		for _, m := range ms {
+			if m.MatchString(s) {
+				return true
+			}
+		}
+		return false
+	}}
+}
+
+func describe(ms []Matcher) string {
+	descs := make([]string, len(ms))
+	for i, m := range ms {
+		descs[i] = m.String()
+	}
+	return strings.Join(descs, ", ")
+}
+
+// LineCount matches text if m matches its decimal number of lines.
+// Usage:
+//
+//	LineCount(Ge(2))
+func LineCount(m Matcher) Matcher {
+	return matcher{fmt.Sprint("line count ", m), func(s string) bool {
+		n := strings.Count(s, "\n")
+		if len(s) > 0 && !strings.HasSuffix(s, "\n") {
+			n++
+		}
+		return m.MatchString(strconv.Itoa(n))
+	}}
+}
+
+// JSONPath matches JSON text if m matches the value at path (see Lookup).
+// Strings are matched without quotes, numbers in their shortest form, and
+// other values as JSON.
+// Usage:
+//
+//	JSONPath("[0].mtu", Eq(1500))
+func JSONPath(path string, m Matcher) Matcher {
+	return matcher{fmt.Sprint(path, " ", m), func(s string) bool {
+		var v interface{}
+		if json.Unmarshal([]byte(s), &v) != nil {
+			return false
+		}
+		v, err := Lookup(v, path)
+		return err == nil && m.MatchString(format(v))
+	}}
+}
+
+func format(v interface{}) string {
+	switch t := v.(type) {
+	case string:
+		return t
+	case float64:
+		return strconv.FormatFloat(t, 'g', -1, 64)
+	case int:
+		return strconv.Itoa(t)
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
+
+// Eq matches text that is a number equal to n.
+func Eq(n float64) Matcher { return compare("==", n) }
+
+// Ne matches text that is a number other than n.
+func Ne(n float64) Matcher { return compare("!=", n) }
+
+// Lt matches text that is a number less than n.
+func Lt(n float64) Matcher { return compare("<", n) }
+
+// Le matches text that is a number less than or equal to n.
+func Le(n float64) Matcher { return compare("<=", n) }
+
+// Gt matches text that is a number greater than n.
+func Gt(n float64) Matcher { return compare(">", n) }
+
+// Ge matches text that is a number greater than or equal to n.
+func Ge(n float64) Matcher { return compare(">=", n) }
+
+func compare(op string, n float64) Matcher {
+	return matcher{fmt.Sprint(op, " ", n), func(s string) bool {
+		x, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+		if err != nil {
+			return false
+		}
+		switch op {
+		case "==":
+			return x == n
+		case "!=":
+			return x != n
+		case "<":
+			return x < n
+		case "<=":
+			return x <= n
+		case ">":
+			return x > n
+		}
+		return x >= n
+	}}
+}
+
+// That asserts that s matches m.
+func (assert Assert) That(s string, m Matcher) {
+	assert.Helper()
+	if !m.MatchString(s) {
+		assert.Fatalf("%q\n\tdoesn't match %v", s, m)
+	}
+}
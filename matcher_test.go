@@ -0,0 +1,53 @@
+// Copyright © 2015-2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package test
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestMatcher(t *testing.T) {
+	const links = `[{"ifname":"lo","mtu":65536,"flags":["UP"]},` +
+		`{"ifname":"eth0","mtu":1500,"flags":["NO-CARRIER","UP"]}]`
+	for _, x := range []struct {
+		m    Matcher
+		s    string
+		want bool
+	}{
+		{Contains("UP"), "LOWER_UP", true},
+		{Not(Contains("DOWN")), "UP", true},
+		{AllOf(Contains("a"), Not(Contains("b"))), "ac", true},
+		{AllOf(Contains("a"), Not(Contains("b"))), "ab", false},
+		{AnyOf(Regexp("^x"), Contains("y")), "ay", true},
+		{AnyOf(Regexp("^x"), Contains("y")), "ax", false},
+		{LineCount(Eq(2)), "one\ntwo\n", true},
+		{LineCount(Eq(2)), "one\ntwo", true},
+		{LineCount(Gt(0)), "", false},
+		{Le(1.5), " 1.5\n", true},
+		{Ne(1), "one", false},
+		{JSONPath("[1].mtu", Eq(1500)), links, true},
+		{JSONPath("[0].ifname", Regexp("^lo$")), links, true},
+		{JSONPath("[1].flags", Contains("NO-CARRIER")), links, true},
+		{JSONPath("[2].mtu", Eq(1500)), links, false},
+		{JSONPath(".mtu", Eq(1500)), "not json", false},
+	} {
+		if got := x.m.MatchString(x.s); got != x.want {
+			t.Errorf("%v: %q: got %v", x.m, x.s, got)
+		}
+	}
+	assert := Assert{t}
+	assert.Equal(AllOf(Contains("a"), Not(Regexp("^b"))).String(),
+		`all(contains "a", not(^b))`)
+	assert.That("10.1.0.1/24", AllOf(Contains("/24"), Not(Contains("::"))))
+	assert.Error(errors.New("no carrier"), Contains("carrier"))
+	assert.Program(AllOf(Contains("a"), Not(Contains("c"))),
+		LineCount(Eq(2)), "printf", `a\nb\n`)
+	assert.ProgramErr(`mismatch "contains \"c\""`, Contains("c"), "true")
+	assert.Program(Stderr{Not(Contains("error"))}, "sh", "-c",
+		"echo warning >&2")
+	_, err := Lookup(map[string]interface{}{"a": []interface{}{1}}, "a[1]")
+	assert.Error(err, "a[1]: not found")
+}
@@ -0,0 +1,197 @@
+// Copyright © 2015-2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package test
+
+import (
+	"bufio"
+	"fmt"
+	"go/build"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+// gomod is the module path, requirements, and replacements parsed from a
+// go.mod file.
+type gomod struct {
+	root    string
+	path    string
+	require map[string]string
+	replace map[string]string
+}
+
+// findModule returns the go.mod of dir or its nearest parent; or nil if
+// there is none.
+func findModule(dir string) (*gomod, error) {
+	for {
+		fn := filepath.Join(dir, "go.mod")
+		if _, err := os.Stat(fn); err == nil {
+			return parseModule(fn)
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil, nil
+		}
+		dir = parent
+	}
+}
+
+func parseModule(fn string) (*gomod, error) {
+	f, err := os.Open(fn)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	mod := &gomod{
+		root:    filepath.Dir(fn),
+		require: make(map[string]string),
+		replace: make(map[string]string),
+	}
+	block := ""
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "//"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		verb := block
+		switch {
+		case fields[0] == ")":
+			block = ""
+			continue
+		case len(fields) == 2 && fields[1] == "(":
+			block = fields[0]
+			continue
+		case len(block) == 0:
+			verb, fields = fields[0], fields[1:]
+		}
+		switch {
+		case verb == "module" && len(fields) > 0:
+			mod.path = strings.Trim(fields[0], `"`)
+		case verb == "require" && len(fields) > 1:
+			mod.require[fields[0]] = fields[1]
+		case verb == "replace":
+			// OLD [VERSION] => NEW [VERSION]
+			for i, field := range fields {
+				if field == "=>" && i+1 < len(fields) {
+					mod.replace[fields[0]] = fields[i+1]
+					if i+2 < len(fields) {
+						mod.replace[fields[0]] += "@" +
+							fields[i+2]
+					}
+				}
+			}
+		}
+	}
+	return mod, scanner.Err()
+}
+
+// dir returns the source directory of the named package within the module,
+// its replacements, vendor directory, or module cache; or "" if it isn't
+// found. Like go, this is the module with the longest path that prefixes
+// the package.
+func (mod *gomod) dir(name string) string {
+	dep := ""
+	for _, deps := range []map[string]string{mod.replace, mod.require} {
+		for path := range deps {
+			if _, ok := within(name, path); ok && len(path) > len(dep) {
+				dep = path
+			}
+		}
+	}
+	if rel, ok := within(name, mod.path); ok && len(mod.path) > len(dep) {
+		return filepath.Join(mod.root, rel)
+	}
+	if dir := filepath.Join(mod.root, "vendor", name); isDir(dir) {
+		return dir
+	}
+	if len(dep) == 0 {
+		return ""
+	}
+	target, found := mod.replace[dep]
+	if !found {
+		target = mod.require[dep]
+	}
+	var dir string
+	if strings.HasPrefix(target, ".") || filepath.IsAbs(target) {
+		dir = target
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(mod.root, dir)
+		}
+	} else {
+		dir = cachedModule(dep, target)
+		if at := strings.IndexByte(target, '@'); at > 0 {
+			dir = cachedModule(target[:at], target[at+1:])
+		}
+	}
+	rel, _ := within(name, dep)
+	if dir = filepath.Join(dir, rel); isDir(dir) {
+		return dir
+	}
+	return ""
+}
+
+// within returns the path of the package relative to the module.
+func within(name, modpath string) (string, bool) {
+	if name == modpath {
+		return ".", true
+	}
+	if strings.HasPrefix(name, modpath+"/") {
+		return filepath.FromSlash(name[len(modpath)+1:]), true
+	}
+	return "", false
+}
+
+// cachedModule is the directory of the module version in GOMODCACHE where
+// upper case letters are escaped as '!' and lower case.
+func cachedModule(modpath, version string) string {
+	cache := os.Getenv("GOMODCACHE")
+	if len(cache) == 0 {
+		gopath := filepath.SplitList(build.Default.GOPATH)
+		if len(gopath) == 0 {
+			return ""
+		}
+		cache = filepath.Join(gopath[0], "pkg", "mod")
+	}
+	var b strings.Builder
+	for _, r := range modpath + "@" + version {
+		if unicode.IsUpper(r) {
+			b.WriteByte('!')
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return filepath.Join(cache, filepath.FromSlash(b.String()))
+}
+
+func isDir(fn string) bool {
+	fi, err := os.Stat(fn)
+	return err == nil && fi.IsDir()
+}
+
+// packageSource returns the directory of the named package from the module
+// of dir or, failing that, GOPATH.
+func packageSource(dir, name string) (string, error) {
+	mod, err := findModule(dir)
+	if err != nil {
+		return "", err
+	}
+	if mod != nil {
+		if src := mod.dir(name); len(src) > 0 {
+			return src, nil
+		}
+	}
+	pkg, err := build.Import(name, dir, build.FindOnly)
+	if err != nil {
+		return "", fmt.Errorf("%s: not found in module or GOPATH: %v",
+			name, err)
+	}
+	return pkg.Dir, nil
+}
@@ -0,0 +1,59 @@
+// Copyright © 2015-2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDir(t *testing.T) {
+	assert := Assert{t}
+	wd, err := os.Getwd()
+	assert.Nil(err)
+	t.Run("netport", func(t *testing.T) {
+		Assert{t}.Dir("github.com/platinasystems/test/netport")
+		dir, err := os.Getwd()
+		Assert{t}.Nil(err)
+		Assert{t}.Equal(dir, filepath.Join(wd, "netport"))
+	})
+	dir, err := os.Getwd()
+	assert.Nil(err)
+	assert.Equal(dir, wd)
+
+	t.Setenv("GOMODCACHE", "/cache")
+	assert.Equal(cachedModule("github.com/BurntSushi/toml", "v0.3.1"),
+		"/cache/github.com/!burnt!sushi/toml@v0.3.1")
+
+	tmp, err := ioutil.TempDir("", "module")
+	assert.Nil(err)
+	defer os.RemoveAll(tmp)
+	assert.Nil(ioutil.WriteFile(filepath.Join(tmp, "go.mod"), []byte(`
+module example.com/m
+
+replace (
+	example.com/local v1.0.0 => ./local // comment
+	example.com/n => ./n
+	example.com/n/sub => ./nsub
+)
+`), 0644))
+	for _, dir := range []string{"local/sub", "vendor/example.com/v", "a",
+		"n/sub/p", "nsub/p"} {
+		assert.Nil(os.MkdirAll(filepath.Join(tmp, dir), 0755))
+	}
+	for name, want := range map[string]string{
+		"example.com/local/sub": "local/sub",
+		"example.com/v":         "vendor/example.com/v",
+		"example.com/m/a":       "a",
+		"example.com/n/sub":     "nsub",
+		"example.com/n/sub/p":   "nsub/p",
+	} {
+		src, err := packageSource(filepath.Join(tmp, "a"), name)
+		assert.Nil(err)
+		assert.Equal(src, filepath.Join(tmp, want))
+	}
+}
@@ -0,0 +1,146 @@
+// Copyright © 2015-2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package test
+
+import (
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// A Neighbor is an ARP or NDP entry of a namespace as shown by "ip -j
+// neigh". As the expectation of Assert.Neighbor, only its non-zero fields
+// are compared.
+type Neighbor struct {
+	Dst    string `json:"dst"`
+	Dev    string `json:"dev,omitempty"`
+	Lladdr string `json:"lladdr,omitempty"`
+	// State is a set of NUD states, e.g. REACHABLE, STALE, FAILED,
+	// PERMANENT; any of those wanted must be present.
+	State []string `json:"state,omitempty"`
+}
+
+func (n Neighbor) String() string {
+	s := n.Dst
+	if len(n.Dev) > 0 {
+		s += " dev " + n.Dev
+	}
+	if len(n.Lladdr) > 0 {
+		s += " lladdr " + n.Lladdr
+	}
+	if len(n.State) > 0 {
+		s += " " + strings.Join(n.State, ",")
+	}
+	return s
+}
+
+// Neighbors returns the IPv4 and IPv6 neighbor tables of the namespace.
+func Neighbors(netns Netns) ([]Neighbor, error) {
+	var neighbors []Neighbor
+	err := IP(netns, &neighbors, "neigh", "show")
+	return neighbors, err
+}
+
+// Match is true if n has the non-zero fields of want; without a wanted
+// State, n must be resolved to a link layer address.
+func (n Neighbor) Match(want Neighbor) bool {
+	if len(want.Dst) > 0 && !sameIP(n.Dst, want.Dst) {
+		return false
+	}
+	if len(want.Dev) > 0 && n.Dev != want.Dev {
+		return false
+	}
+	if len(want.Lladdr) > 0 && !strings.EqualFold(n.Lladdr, want.Lladdr) {
+		return false
+	}
+	if len(want.State) == 0 {
+		return len(n.Lladdr) > 0
+	}
+	for _, state := range want.State {
+		for _, have := range n.State {
+			if strings.EqualFold(have, state) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Neighbor asserts that the namespace has an entry for addr with options:
+//
+//	Neighbor
+//		the entry must match these non-zero fields
+//	time.Duration
+//		wait up to this long for the entry, e.g. to be resolved
+//
+// It returns the entry. Failures show the neighbor table.
+// Usage:
+//
+//	assert.Neighbor("h1", "10.1.0.2", Neighbor{Dev: "eth1.10",
+//		State: []string{"REACHABLE", "STALE"}}, 3*time.Second)
+func (assert Assert) Neighbor(netns, addr string,
+	options ...interface{}) Neighbor {
+	assert.Helper()
+	want := Neighbor{}
+	var wait time.Duration
+	for _, option := range options {
+		switch t := option.(type) {
+		case Neighbor:
+			want = t
+		case time.Duration:
+			wait = t
+		default:
+			assert.Fatalf("unexpected option: %T", t)
+			return Neighbor{}
+		}
+	}
+	want.Dst = addr
+	var found *Neighbor
+	var neighbors []Neighbor
+	var err error
+	cond := func() (interface{}, bool) {
+		if neighbors, err = Neighbors(Netns(netns)); err != nil {
+			return err, true
+		}
+		for i := range neighbors {
+			if neighbors[i].Match(want) {
+				found = &neighbors[i]
+				return found, true
+			}
+		}
+		return nil, false
+	}
+	if wait > 0 {
+		Eventually(cond, wait, ProbePeriod)
+	} else {
+		cond()
+	}
+	if err != nil {
+		assert.Fatal(err)
+		return Neighbor{}
+	}
+	if found == nil {
+		lines := make([]string, len(neighbors))
+		for i, n := range neighbors {
+			lines[i] = n.String()
+		}
+		assert.Fatalf("%s: no neighbor %v in\n\t%s", Netns(netns), want,
+			strings.Join(lines, "\n\t"))
+		return Neighbor{}
+	}
+	return *found
+}
+
+// FlushNeighbors asserts the removal of all but the permanent entries of
+// the namespace's neighbor tables, e.g. between tests.
+func (assert Assert) FlushNeighbors(netns string) {
+	assert.Helper()
+	for _, family := range []string{"-4", "-6"} {
+		cmd := exec.Command("ip", family, "neigh", "flush", "all")
+		if _, err := Netns(netns).Output(cmd); err != nil {
+			assert.Fatal(cmd.Args, err)
+		}
+	}
+}
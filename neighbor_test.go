@@ -0,0 +1,31 @@
+// Copyright © 2015-2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package test
+
+import "testing"
+
+func TestNeighbor(t *testing.T) {
+	ns := testNetns(t)
+	assert := Assert{t}
+	assert.Program("ip", "-n", ns, "neigh", "add", "10.9.0.5",
+		"lladdr", "02:00:00:00:00:05", "dev", "d0", "nud", "permanent")
+	assert.Program("ip", "-n", ns, "neigh", "add", "10.9.0.6",
+		"lladdr", "02:00:00:00:00:06", "dev", "d0", "nud", "stale")
+
+	n := assert.Neighbor(ns, "10.9.0.5", Neighbor{
+		Dev:    "d0",
+		Lladdr: "02:00:00:00:00:05",
+		State:  []string{"permanent"},
+	})
+	assert.Equal(n.String(), "10.9.0.5 dev d0 lladdr 02:00:00:00:00:05 PERMANENT")
+	assert.Neighbor(ns, "10.9.0.6", Neighbor{
+		State: []string{"REACHABLE", "STALE"},
+	}, ProbePeriod)
+
+	assert.FlushNeighbors(ns)
+	neighbors, err := Neighbors(Netns(ns))
+	assert.Nil(err)
+	assert.DeepEqual(neighbors, []Neighbor{n})
+}
@@ -0,0 +1,131 @@
+// Copyright © 2015-2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package test
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"syscall"
+)
+
+// Netns names a network namespace in /var/run/netns or, if it begins with
+// '/', the path of a namespace file like /proc/PID/ns/net. An empty name or
+// "default" is the namespace of the test.
+//
+// Programs begun with a Netns option are started from an OS thread that has
+// entered the namespace, rather than through "ip netns exec". Unlike the
+// latter, only the network namespace is entered; /sys isn't remounted and
+// /etc/netns/NAME isn't bound over /etc, so programs that read
+// /sys/class/net or /etc/resolv.conf see those of the host. Use netlink,
+// e.g. "ip link show", or /proc/self/net instead.
+type Netns string
+
+func (ns Netns) String() string {
+	if ns.IsDefault() {
+		return "default"
+	}
+	return string(ns)
+}
+
+// IsDefault is true if the namespace is that of the test.
+func (ns Netns) IsDefault() bool {
+	return len(ns) == 0 || ns == "default"
+}
+
+// Path of the namespace file.
+func (ns Netns) Path() string {
+	if strings.HasPrefix(string(ns), "/") {
+		return string(ns)
+	}
+	return filepath.Join("/var/run/netns", string(ns))
+}
+
+// Do calls f from an OS thread switched to the network namespace. The
+// thread is restored to its original namespace or, failing that, retired
+// so that it isn't reused by other goroutines.
+func (ns Netns) Do(f func() error) error {
+	if ns.IsDefault() {
+		return f()
+	}
+	nsf, err := os.Open(ns.Path())
+	if err != nil {
+		return err
+	}
+	defer nsf.Close()
+	done := make(chan error)
+	go func() {
+		runtime.LockOSThread()
+		fn := fmt.Sprint("/proc/self/task/", syscall.Gettid(), "/ns/net")
+		self, err := os.Open(fn)
+		if err != nil {
+			runtime.UnlockOSThread()
+			done <- err
+			return
+		}
+		defer self.Close()
+		if err = setns(nsf.Fd()); err != nil {
+			runtime.UnlockOSThread()
+			done <- fmt.Errorf("netns %s: %v", ns, err)
+			return
+		}
+		err = f()
+		if setns(self.Fd()) == nil {
+			runtime.UnlockOSThread()
+		}
+		done <- err
+	}()
+	return <-done
+}
+
+// Start cmd within the network namespace.
+func (ns Netns) Start(cmd *exec.Cmd) error {
+	return ns.Do(cmd.Start)
+}
+
+// Run cmd within the network namespace and wait for it to finish.
+func (ns Netns) Run(cmd *exec.Cmd) error {
+	if err := ns.Start(cmd); err != nil {
+		return err
+	}
+	return cmd.Wait()
+}
+
+// Output runs cmd within the network namespace and returns its Stdout.
+func (ns Netns) Output(cmd *exec.Cmd) ([]byte, error) {
+	var stdout, stderr strings.Builder
+	cmd.Stdout = &stdout
+	if cmd.Stderr == nil {
+		cmd.Stderr = &stderr
+	}
+	err := ns.Run(cmd)
+	if ee, ok := err.(*exec.ExitError); ok && stderr.Len() > 0 {
+		ee.Stderr = []byte(stderr.String())
+	}
+	return []byte(stdout.String()), err
+}
+
+// The syscall package doesn't define SYS_SETNS for all architectures.
+var sysSetns = map[string]uintptr{
+	"386":     346,
+	"amd64":   308,
+	"arm":     375,
+	"arm64":   268,
+	"ppc64le": 350,
+}[runtime.GOARCH]
+
+func setns(fd uintptr) error {
+	if sysSetns == 0 {
+		return syscall.ENOSYS
+	}
+	_, _, e := syscall.RawSyscall(sysSetns, fd, syscall.CLONE_NEWNET, 0)
+	if e != 0 {
+		return e
+	}
+	return nil
+}
@@ -8,6 +8,7 @@
 package netport
 
 import (
+	"flag"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -23,6 +24,11 @@ const NetPortFile = "testdata/netport.yaml"
 var Goes string
 var PortByNetPort, NetPortByPort map[string]string
 
+// VerifyFlag enables the NetDevs.Test assertions of each interface's link,
+// master, address, and routes; these need an iproute2 with `ip -j`.
+var VerifyFlag = flag.Bool("test.netport.verify", false,
+	"verify the links, addresses and routes of NetDevs with ip -j")
+
 func Init(goes string) {
 	Goes = goes
 	b, err := ioutil.ReadFile(NetPortFile)
@@ -134,6 +140,10 @@ func (netdevs NetDevs) Test(t *testing.T, tests ...test.Tester) {
 				"link", "set", nd.Ifname, "up")
 			defer cleanup.Program(Goes, "ip", "-n", ns,
 				"link", "del", nd.Ifname)
+			if *VerifyFlag {
+				assert.LinkUp(ns, nd.Ifname,
+					test.Link{Kind: "bridge"})
+			}
 		} else {
 			ifname := PortByNetPort[nd.NetPort]
 			if nd.Vlan != 0 {
@@ -152,6 +162,11 @@ func (netdevs NetDevs) Test(t *testing.T, tests ...test.Tester) {
 				nd.Ifname, "up", "netns", ns)
 			defer cleanup.Program(Goes, "ip", "-n", ns,
 				"link", "set", nd.Ifname, "down", "netns", 1)
+			if *VerifyFlag {
+				assert.LinkUp(ns, nd.Ifname, test.Link{
+					VlanID: nd.Vlan,
+				})
+			}
 		}
 
 		if nd.DevType == NETPORT_DEVTYPE_BRIDGE_PORT {
@@ -159,16 +174,27 @@ func (netdevs NetDevs) Test(t *testing.T, tests ...test.Tester) {
 				"link", "set", nd.Ifname, "master", nd.Upper)
 			defer cleanup.Program(Goes, "ip", "-n", ns,
 				"link", "set", nd.Ifname, "nomaster")
+			if *VerifyFlag {
+				assert.Master(ns, nd.Ifname, nd.Upper)
+			}
 		} else if nd.Ifa != "" {
 			assert.ProgramRetry(3, Goes, "ip", "-n", ns,
 				"address", "add", nd.Ifa, "dev", nd.Ifname)
 			defer cleanup.Program(Goes, "ip", "-n", ns,
 				"address", "del", nd.Ifa, "dev", nd.Ifname)
+			if *VerifyFlag {
+				assert.Addr(ns, nd.Ifname, nd.Ifa)
+			}
 			for _, route := range nd.Routes {
 				prefix := route.Prefix
 				gw := route.GW
 				assert.Program(Goes, "ip", "-n", ns,
 					"route", "add", prefix, "via", gw)
+				if *VerifyFlag {
+					assert.Route(ns, prefix, test.Route{
+						Gateway: gw,
+					})
+				}
 			}
 		}
 		if *test.VVV {
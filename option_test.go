@@ -0,0 +1,46 @@
+// Copyright © 2015-2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package test
+
+import (
+	"testing"
+	"time"
+)
+
+func TestUnexpectedOption(t *testing.T) {
+	for name, f := range map[string]func(Assert){
+		"Listener": func(assert Assert) {
+			assert.Listener("@test-option", Owner(1), "bogus")
+		},
+		"NoListener": func(assert Assert) {
+			assert.NoListener("@test-option", "bogus")
+		},
+		"Ping": func(assert Assert) {
+			_, err := Ping("", "127.0.0.1", "bogus")
+			assert.Nil(err)
+		},
+		"NoPing": func(assert Assert) {
+			assert.NoPing("", "127.0.0.1", 0, "bogus")
+		},
+		"Neighbor": func(assert Assert) {
+			assert.Neighbor("", "10.9.0.5", "bogus")
+		},
+		"LinkUp": func(assert Assert) {
+			assert.LinkUp("", "lo", "bogus")
+		},
+		"Eventually": func(assert Assert) {
+			assert.Eventually(func() (interface{}, bool) {
+				return nil, true
+			}, time.Second, ProbePeriod, "bogus")
+		},
+	} {
+		tb := &fakeTB{TB: t}
+		f(Assert{tb})
+		if !tb.failed || len(tb.out) == 0 ||
+			tb.out[0] != "unexpected option: string" {
+			t.Errorf("%s: %q", name, tb.out)
+		}
+	}
+}
@@ -0,0 +1,297 @@
+// Copyright © 2015-2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package test
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PingTimeout is the time allowed for a Ping response.
+const PingTimeout = 3 * time.Second
+
+// PingSource option is the source address or interface name of the echo
+// requests (ping -I).
+type PingSource string
+
+// PingSize option is the payload size of each echo request (ping -s).
+type PingSize int
+
+// DontFragment option prohibits fragmentation of the echo requests (ping
+// -M do), e.g. to check the MTU of a path with PingSize.
+type DontFragment struct{}
+
+// PingCount option is the number of echo requests instead of 1.
+type PingCount int
+
+// PingInterval option is the time between echo requests instead of 1s.
+type PingInterval time.Duration
+
+// MaxLoss option is the acceptable percentage of lost packets instead of 0.
+type MaxLoss float64
+
+// PingStats summarizes the replies of a Ping.
+type PingStats struct {
+	Transmitted, Received int
+	// Loss is the percentage of unanswered echo requests.
+	Loss                float64
+	Min, Avg, Max, Mdev time.Duration
+	// RTTs of each reply received by a NativePinger.
+	RTTs []time.Duration
+}
+
+func (stats *PingStats) String() string {
+	return fmt.Sprintf("%d transmitted, %d received, %g%% loss, "+
+		"rtt min/avg/max/mdev %v/%v/%v/%v", stats.Transmitted,
+		stats.Received, stats.Loss, stats.Min, stats.Avg, stats.Max,
+		stats.Mdev)
+}
+
+type pingOptions struct {
+	source       PingSource
+	size         PingSize
+	dontFragment bool
+	count        PingCount
+	interval     PingInterval
+	maxLoss      MaxLoss
+	pinger       Pinger
+}
+
+func newPingOptions(options []interface{}) (*pingOptions, error) {
+	po := &pingOptions{count: 1}
+	for _, option := range options {
+		switch t := option.(type) {
+		case PingSource:
+			po.source = t
+		case PingSize:
+			po.size = t
+		case DontFragment:
+			po.dontFragment = true
+		case PingCount:
+			po.count = t
+		case PingInterval:
+			po.interval = t
+		case MaxLoss:
+			po.maxLoss = t
+		case Pinger:
+			po.pinger = t
+		default:
+			return nil, fmt.Errorf("unexpected option: %T", t)
+		}
+	}
+	return po, nil
+}
+
+// args of the ping command; IPv6 addresses are pinged with -6.
+func (po *pingOptions) args(addr string) []string {
+	args := []string{"ping", "-q", "-c", fmt.Sprint(po.count), "-W", "1"}
+	if strings.Contains(addr, ":") {
+		args = append(args, "-6")
+	}
+	if len(po.source) > 0 {
+		args = append(args, "-I", string(po.source))
+	}
+	if po.size > 0 {
+		args = append(args, "-s", fmt.Sprint(po.size))
+	}
+	if po.dontFragment {
+		args = append(args, "-M", "do")
+	}
+	if po.interval > 0 {
+		args = append(args, "-i", strconv.FormatFloat(
+			time.Duration(po.interval).Seconds(), 'f', -1, 64))
+	}
+	return append(args, addr)
+}
+
+// duration is the expected time to send all echo requests.
+func (po *pingOptions) duration() time.Duration {
+	interval := time.Second
+	if po.interval > 0 {
+		interval = time.Duration(po.interval)
+	}
+	return time.Duration(po.count-1) * interval
+}
+
+// check returns an error if the loss exceeds the limit.
+func (po *pingOptions) check(stats *PingStats) error {
+	if stats.Received == 0 || stats.Loss > float64(po.maxLoss) {
+		return fmt.Errorf("%g%% packet loss", stats.Loss)
+	}
+	return nil
+}
+
+// Ping the address from the network namespace with PingSource, PingSize,
+// DontFragment, PingCount, PingInterval, MaxLoss, and Pinger options. The
+// error is that of the Pinger if it couldn't send requests, or loss in
+// excess of MaxLoss.
+func Ping(netns, addr string, options ...interface{}) (*PingStats, error) {
+	po, err := newPingOptions(options)
+	if err != nil {
+		return nil, err
+	}
+	return po.ping(Netns(netns), addr)
+}
+
+func (po *pingOptions) ping(netns Netns, addr string) (*PingStats, error) {
+	switch t := po.pinger.(type) {
+	case nil:
+		if _, ok := pinger().(NativePinger); ok {
+			return po.native(netns, addr)
+		}
+		return po.program(netns, addr)
+	case ProgramPinger:
+		return po.program(netns, addr)
+	case NativePinger:
+		return po.native(netns, addr)
+	default:
+		return t.Ping(netns, addr, po.options()...)
+	}
+}
+
+// options reconstructs the option list for other Pingers.
+func (po *pingOptions) options() []interface{} {
+	options := []interface{}{po.count, po.maxLoss}
+	if len(po.source) > 0 {
+		options = append(options, po.source)
+	}
+	if po.size > 0 {
+		options = append(options, po.size)
+	}
+	if po.dontFragment {
+		options = append(options, DontFragment{})
+	}
+	if po.interval > 0 {
+		options = append(options, po.interval)
+	}
+	return options
+}
+
+// program runs ping.
+func (po *pingOptions) program(netns Netns, addr string) (*PingStats,
+	error) {
+	xargs := po.args(addr)
+	output, err := netns.Output(exec.Command(xargs[0], xargs[1:]...))
+	stats, perr := parsePing(string(output))
+	if perr != nil {
+		if ee, ok := err.(*exec.ExitError); ok && len(ee.Stderr) > 0 {
+			return nil, errors.New(strings.TrimSpace(
+				string(ee.Stderr)))
+		}
+		if err != nil {
+			return nil, err
+		}
+		return nil, perr
+	}
+	return stats, po.check(stats)
+}
+
+var (
+	pingPacketsRE = regexp.MustCompile(
+		`(\d+) packets transmitted, (\d+) (?:packets )?received.* ([\d.]+)% packet loss`)
+	pingRttRE = regexp.MustCompile(
+		`min/avg/max(?:/mdev)? = ([\d.]+)/([\d.]+)/([\d.]+)(?:/([\d.]+))? ms`)
+)
+
+// parsePing parses the summary of iputils or busybox ping.
+func parsePing(output string) (*PingStats, error) {
+	m := pingPacketsRE.FindStringSubmatch(output)
+	if m == nil {
+		return nil, fmt.Errorf("no ping statistics in %q", output)
+	}
+	stats := new(PingStats)
+	stats.Transmitted, _ = strconv.Atoi(m[1])
+	stats.Received, _ = strconv.Atoi(m[2])
+	stats.Loss, _ = strconv.ParseFloat(m[3], 64)
+	if m = pingRttRE.FindStringSubmatch(output); m != nil {
+		for i, p := range []*time.Duration{
+			&stats.Min, &stats.Avg, &stats.Max, &stats.Mdev,
+		} {
+			ms, _ := strconv.ParseFloat(m[i+1], 64)
+			*p = time.Duration(ms * float64(time.Millisecond))
+		}
+	}
+	return stats, nil
+}
+
+// PingNonFatal is true if the address responds to a Ping with the given
+// options.
+func (assert Assert) PingNonFatal(netns, addr string,
+	options ...interface{}) bool {
+	assert.Helper()
+	po, err := newPingOptions(options)
+	if err != nil {
+		assert.Fatal(err)
+		return false
+	}
+	_, err = po.ping(Netns(netns), addr)
+	return err == nil
+}
+
+// Ping asserts that the address responds w/in PingTimeout, plus the time to
+// send PingCount requests, with no more than MaxLoss; it returns the
+// statistics of the successful run. The options are those of Ping.
+// Usage:
+//
+//	assert.Ping("R1", "fe80::1%eth1", PingSource("eth1"))
+//	assert.Ping("R1", "10.1.0.2", PingSize(9000-28), DontFragment{},
+//		PingCount(10), PingInterval(200*time.Millisecond), MaxLoss(10))
+func (assert Assert) Ping(netns, addr string,
+	options ...interface{}) *PingStats {
+	assert.Helper()
+	po, err := newPingOptions(options)
+	if err != nil {
+		assert.Fatal(err)
+		return nil
+	}
+	xargs := po.args(addr)
+	if *VVV {
+		assert.Log(Netns(netns), xargs)
+	}
+	var stats *PingStats
+	err = Eventually(func() (interface{}, bool) {
+		var err error
+		stats, err = po.ping(Netns(netns), addr)
+		return err, err == nil
+	}, PingTimeout+po.duration(), 250*time.Millisecond)
+	if err != nil {
+		assert.Fatalf("%s no response: %v", addr, err)
+	}
+	return stats
+}
+
+// NoPing asserts that the given address doesn't respond for the duration,
+// e.g. to prove that isolated VLANs stay unreachable. The options are those
+// of Ping.
+func (assert Assert) NoPing(netns, addr string, duration time.Duration,
+	options ...interface{}) {
+	assert.Helper()
+	po, err := newPingOptions(options)
+	if err != nil {
+		assert.Fatal(err)
+		return
+	}
+	if *VVV {
+		assert.Log(Netns(netns), "no", po.args(addr))
+	}
+	err = Consistently(func() (interface{}, bool) {
+		stats, err := po.ping(Netns(netns), addr)
+		if stats == nil {
+			// unreachable unless ping didn't run
+			_, failed := err.(*exec.Error)
+			return err, !failed
+		}
+		return stats, stats.Received == 0
+	}, duration, 250*time.Millisecond)
+	if err != nil {
+		assert.Fatalf("%s reachable from %s: %v", addr, Netns(netns),
+			err)
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright © 2015-2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package test
+
+import (
+	"fmt"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestParsePing(t *testing.T) {
+	assert := Assert{t}
+	stats, err := parsePing(`PING fe80::1%eth1 (fe80::1%eth1) 56 data bytes
+
+--- fe80::1%eth1 ping statistics ---
+10 packets transmitted, 9 received, 10% packet loss, time 1809ms
+rtt min/avg/max/mdev = 0.035/0.046/0.057/0.010 ms
+`)
+	assert.Nil(err)
+	assert.DeepEqual(stats, &PingStats{
+		Transmitted: 10,
+		Received:    9,
+		Loss:        10,
+		Min:         35 * time.Microsecond,
+		Avg:         46 * time.Microsecond,
+		Max:         57 * time.Microsecond,
+		Mdev:        10 * time.Microsecond,
+	})
+	po, err := newPingOptions([]interface{}{MaxLoss(10)})
+	assert.Nil(err)
+	assert.Nil(po.check(stats))
+	po, err = newPingOptions(nil)
+	assert.Nil(err)
+	assert.Error(po.check(stats), "10% packet loss")
+
+	stats, err = parsePing(`--- 10.1.0.2 ping statistics ---
+1 packets transmitted, 0 packets received, 100% packet loss
+`)
+	assert.Nil(err)
+	assert.Equal(stats.String(), "1 transmitted, 0 received, 100% loss, "+
+		"rtt min/avg/max/mdev 0s/0s/0s/0s")
+	assert.Error(po.check(stats), "100% packet loss")
+}
+
+func TestPingArgs(t *testing.T) {
+	assert := Assert{t}
+	po, err := newPingOptions([]interface{}{
+		PingSource("eth1"), PingSize(8972), DontFragment{},
+		PingCount(5), PingInterval(200 * time.Millisecond),
+	})
+	assert.Nil(err)
+	assert.Equal(fmt.Sprint(po.args("2001:db8::1")),
+		"[ping -q -c 5 -W 1 -6 -I eth1 -s 8972 -M do -i 0.2 2001:db8::1]")
+}
+
+func TestNativePinger(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("needs CAP_NET_RAW")
+	}
+	assert := Assert{t}
+	stats := assert.Ping("", "127.0.0.1", NativePinger{}, PingCount(3),
+		PingInterval(10*time.Millisecond), PingSize(1000),
+		DontFragment{})
+	assert.Equal(fmt.Sprint(stats.Received, len(stats.RTTs)), "3 3")
+	if stats.Min > stats.Avg || stats.Avg > stats.Max {
+		t.Fatal(stats)
+	}
+	_, err := NativePinger{}.Ping("", "127.0.0.1", PingSource("lo"))
+	assert.Nil(err)
+}
+
+func TestEchoRequest(t *testing.T) {
+	b := echoRequest(false, 0x1234, 1, 4)
+	Assert{t}.Equal(fmt.Sprintf("% x", b),
+		"08 00 e3 c6 12 34 00 01 00 01 02 03")
+	Assert{t}.Equal(fmt.Sprint(checksum(b)), "0")
+}
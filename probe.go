@@ -0,0 +1,64 @@
+// Copyright © 2015-2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package test
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"time"
+)
+
+// ProbePeriod is the interval between WaitFor probes.
+const ProbePeriod = 100 * time.Millisecond
+
+// A Probe returns nil when its condition is met, e.g. the readiness of a
+// Daemon; otherwise, an error describing what's missing.
+type Probe func() error
+
+var errExited = errors.New("exited")
+
+// WaitFor polls the probe until it succeeds or the timeout expires; then
+// returns the last probe error.
+func WaitFor(timeout time.Duration, probe Probe) error {
+	var err error
+	if Eventually(func() (interface{}, bool) {
+		err = probe()
+		return err, err == nil || err == errExited
+	}, timeout, ProbePeriod) != nil {
+		return fmt.Errorf("%v after %v", err, timeout)
+	}
+	return err
+}
+
+// UnixListener probes for the named Unix socket; this is the inverse of
+// Assert.NoListener.
+func UnixListener(atsockname string) Probe {
+	return func() error {
+		_, err := listening(Socket{Proto: "unix", Addr: atsockname},
+			"", 0)
+		return err
+	}
+}
+
+// NetnsListener probes for a listener on the Socket pattern within the given
+// network namespace; e.g. that of a routing daemon's container.
+func NetnsListener(netns Netns, sock Socket) Probe {
+	return func() error {
+		_, err := listening(sock, netns, 0)
+		return err
+	}
+}
+
+// TCPListener probes for a connection to the given address.
+func TCPListener(addr string) Probe {
+	return func() error {
+		conn, err := net.DialTimeout("tcp", addr, time.Second)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	}
+}
@@ -0,0 +1,38 @@
+// Copyright © 2015-2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package test
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestProbes(t *testing.T) {
+	assert := Assert{t}
+	ln, err := net.Listen("tcp4", "127.0.0.1:0")
+	assert.Nil(err)
+	addr := ln.Addr().String()
+	assert.Nil(TCPListener(addr)())
+	assert.Nil(NetnsListener("", Socket{Proto: "tcp", Addr: addr})())
+	ln.Close()
+	assert.NonNil(TCPListener(addr)())
+
+	name := fmt.Sprint("@test-probe-", os.Getpid())
+	assert.NonNil(UnixListener(name)())
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		if ln, err := net.Listen("unix", name); err == nil {
+			time.Sleep(time.Second)
+			ln.Close()
+		}
+	}()
+	assert.Nil(WaitFor(time.Second, UnixListener(name)))
+
+	err = WaitFor(200*time.Millisecond, TCPListener(addr))
+	assert.Error(err, Contains("after 200ms"))
+}
@@ -0,0 +1,87 @@
+// Copyright © 2015-2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package test
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// setpgid has cmd start as the leader of a new process group so that
+// signals reach any descendants, e.g. the ping of "ip netns exec".
+func setpgid(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = new(syscall.SysProcAttr)
+	}
+	cmd.SysProcAttr.Setpgid = true
+}
+
+// signalGroup sends sig to every process in the group led by cmd.
+func signalGroup(cmd *exec.Cmd, sig syscall.Signal) error {
+	if cmd.Process == nil {
+		return nil
+	}
+	return syscall.Kill(-cmd.Process.Pid, sig)
+}
+
+// reapGroup kills any processes remaining in the given group after its
+// leader has exited and returns a description of each of these survivors.
+func reapGroup(pgid int) []string {
+	survivors := groupSurvivors(pgid)
+	if len(survivors) > 0 {
+		syscall.Kill(-pgid, syscall.SIGKILL)
+	}
+	return survivors
+}
+
+// groupSurvivors describes the processes remaining in the given group.
+func groupSurvivors(pgid int) []string {
+	if syscall.Kill(-pgid, 0) != nil {
+		return nil
+	}
+	var survivors []string
+	for _, pid := range groupMembers(pgid) {
+		cmdline, _ := ioutil.ReadFile(filepath.Join("/proc",
+			strconv.Itoa(pid), "cmdline"))
+		cmdline = bytes.TrimRight(cmdline, "\x00")
+		cmdline = bytes.Replace(cmdline, []byte{0}, []byte{' '}, -1)
+		survivors = append(survivors, fmt.Sprint(pid, " ", string(cmdline)))
+	}
+	return survivors
+}
+
+// groupMembers scans /proc/PID/stat for live processes in the given group.
+func groupMembers(pgid int) []int {
+	var pids []int
+	stats, _ := filepath.Glob("/proc/[0-9]*/stat")
+	for _, fn := range stats {
+		b, err := ioutil.ReadFile(fn)
+		if err != nil {
+			continue
+		}
+		// the state and pgrp are the first and third fields after
+		// the parenthesized comm
+		i := bytes.LastIndexByte(b, ')')
+		if i < 0 {
+			continue
+		}
+		fields := strings.Fields(string(b[i+1:]))
+		if len(fields) < 3 || fields[2] != strconv.Itoa(pgid) ||
+			fields[0] == "Z" {
+			continue
+		}
+		pid, err := strconv.Atoi(filepath.Base(filepath.Dir(fn)))
+		if err == nil {
+			pids = append(pids, pid)
+		}
+	}
+	return pids
+}
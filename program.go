@@ -5,10 +5,11 @@
 package test
 
 import (
-	"bytes"
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
+	"os"
 	"os/exec"
 	"regexp"
 	"strings"
@@ -22,6 +23,28 @@ const Timeout = 3 * time.Second
 
 type Quiet struct{}
 
+// Interactive Programs read Stdin from Send instead of /dev/null or an
+// io.Reader option.
+type Interactive struct{}
+
+// Env lists "NAME=VALUE" entries added to a Program's environment.
+type Env []string
+
+// Dir is a Program's working directory.
+type Dir string
+
+// ExitStatus is the expected exit code of a Program instead of 0.
+type ExitStatus int
+
+// Stderr matches a Program's error output, e.g. with a compiled regex
+// pattern, instead of failing if there's any.
+type Stderr struct {
+	Matcher
+}
+
+// AllowStderr doesn't fail a Program for writing to Stderr.
+type AllowStderr struct{}
+
 // Begin a Program; type options:
 //
 //	Quiet
@@ -29,37 +52,90 @@ type Quiet struct{}
 //	io.Reader
 //		use reader as Stdin instead of the default, /dev/null
 //
-//	*regexp.Regexp
-//		match Stdout with compiled regex pattern
+//	Interactive
+//		use Send to write Stdin and Expect to synchronize with Stdout
+//
+//	Matcher
+//		match Stdout, e.g. with a compiled regex pattern; if given
+//		more than once, Stdout must match all
 //
 //	time.Duration
 //		wait up to the given duration for the program to finish instead
 //		of the default Timeout
+//
+//	Stream
+//		log each line of Stdout and Stderr as it's received; this is the
+//		default for all but Quiet programs with -test.stream
+//
+//	Env
+//		add these "NAME=VALUE" entries to the inherited environment
+//
+//	Dir
+//		run the program in this working directory
+//
+//	Netns
+//		run the program in this network namespace
+//
+//	ExitStatus
+//		expect the program to exit with this code instead of 0
+//
+//	Stderr
+//		match Stderr instead of failing if there is any error output
+//
+//	AllowStderr
+//		don't fail if there is any error output
+//
+//	Budget
+//		fail if the program exceeds any of these resource limits
 func Begin(tb testing.TB, options ...interface{}) (*Program, error) {
 	var (
 		stdin io.Reader
 		args  []string
+		env   []string
+		dir   Dir
 	)
 	p := &Program{
 		tb:   tb,
-		obuf: new(bytes.Buffer),
-		ebuf: new(bytes.Buffer),
+		obuf: new(syncBuffer),
+		ebuf: new(syncBuffer),
 		dur:  Timeout,
+		done: make(chan struct{}),
 	}
 	for _, opt := range options {
 		switch t := opt.(type) {
 		case Quiet:
 			p.quiet = true
+		case Interactive:
+			p.interactive = true
 		case io.Reader:
 			stdin = t
-		case *regexp.Regexp:
-			p.exp = t
 		case string:
 			args = append(args, t)
 		case []string:
 			args = append(args, t...)
 		case time.Duration:
 			p.dur = t
+		case Stream:
+			p.stream = &t
+		case Env:
+			env = append(env, t...)
+		case Dir:
+			dir = t
+		case Netns:
+			p.netns = t
+		case ExitStatus:
+			p.exit = int(t)
+		case Stderr:
+			p.stderr = t.Matcher
+		case AllowStderr:
+			p.allowStderr = true
+		case Budget:
+			p.budget = &t
+		case Matcher:
+			if p.exp != nil {
+				t = AllOf(p.exp, t)
+			}
+			p.exp = t
 		default:
 			args = append(args, fmt.Sprint(t))
 		}
@@ -69,163 +145,362 @@ func Begin(tb testing.TB, options ...interface{}) (*Program, error) {
 	}
 	// preface output with newline for pretty logging
 	p.obuf.WriteRune('\n')
+	p.mark = p.obuf.Len()
 	p.cmd = exec.Command(args[0], args[1:]...)
 	p.cmd.Stdin = stdin
+	if p.interactive {
+		p.cmd.Stdin = nil
+		stdin, err := p.cmd.StdinPipe()
+		if err != nil {
+			return p, err
+		}
+		p.stdin = stdin
+	}
+	p.cmd.Dir = string(dir)
+	if len(env) > 0 {
+		p.cmd.Env = append(os.Environ(), env...)
+	}
 	p.cmd.Stdout = p.obuf
 	p.cmd.Stderr = p.ebuf
+	if p.stream == nil && *StreamFlag && !p.quiet {
+		p.stream = &Stream{}
+	}
+	if p.stream != nil {
+		log := p.stream.Log
+		if log == nil {
+			log = tb.Log
+		}
+		p.lws = []*lineWriter{
+			{w: p.obuf, log: log, pid: p.Pid, name: "stdout"},
+			{w: p.ebuf, log: log, pid: p.Pid, name: "stderr"},
+		}
+		p.cmd.Stdout = p.lws[0]
+		p.cmd.Stderr = p.lws[1]
+	}
 	if *VVV {
 		tb.Helper()
-		tb.Log(args)
+		if p.netns.IsDefault() {
+			tb.Log(args)
+		} else {
+			tb.Log(p.netns, args)
+		}
+	}
+	take, err := replaying(tb.Name(), args, p.netns)
+	if err != nil {
+		close(p.done)
+		return p, err
+	}
+	if take != nil {
+		p.replay(take)
+		return p, nil
+	}
+	if p.take, err = recording(tb.Name(), args, p.netns); err != nil {
+		close(p.done)
+		return p, err
+	}
+	if p.take != nil && stdin != nil {
+		p.cmd.Stdin = io.TeeReader(stdin, &p.sent)
 	}
-	return p, p.cmd.Start()
+	setpgid(p.cmd)
+	p.start = time.Now()
+	if err := p.netns.Start(p.cmd); err != nil {
+		p.waitErr = err
+		close(p.done)
+		return p, err
+	}
+	go p.wait()
+	return p, nil
 }
 
 // Program is an exec.Cmd wrapper
 type Program struct {
 	cmd   *exec.Cmd
 	tb    testing.TB
-	obuf  *bytes.Buffer
-	ebuf  *bytes.Buffer
+	obuf  *syncBuffer
+	ebuf  *syncBuffer
 	dur   time.Duration
-	exp   *regexp.Regexp
+	exp   Matcher
 	quiet bool
+
+	stream *Stream
+	lws    []*lineWriter
+
+	exit        int
+	stderr      Matcher
+	allowStderr bool
+
+	start  time.Time
+	result *Result
+	ended  bool
+	endErr error // of the first End
+
+	done    chan struct{}
+	waitErr error
+
+	interactive bool
+	stdin       io.WriteCloser
+	mark        int // of Stdout consumed by Expect
+
+	survivors []string // of the process group after exit
+	quit      bool
+
+	budget *Budget
+	netns  Netns
+
+	take     *Take // of -test.record or -test.replay
+	replayed bool
+	sent     syncBuffer // recorded Stdin
 }
 
-// Quit will SIGTERM the Program then End and Log any error.
+// wait for the program to exit then close its output buffers to signal
+// any Expect.
+func (p *Program) wait() {
+	p.waitErr = p.cmd.Wait()
+	p.survivors = groupSurvivors(p.cmd.Process.Pid)
+	p.obuf.Close()
+	p.ebuf.Close()
+	close(p.done)
+}
+
+// Quit will SIGTERM the Program's process group then End and Log any error.
 func (p *Program) Quit() {
+	if p == nil {
+		return
+	}
 	p.tb.Helper()
-	p.cmd.Process.Signal(syscall.SIGTERM)
+	p.quit = true
+	signalGroup(p.cmd, syscall.SIGTERM)
+	if p.ended {
+		if p.cmd.Process != nil {
+			reapGroup(p.cmd.Process.Pid)
+		}
+		return
+	}
 	if err := p.End(); err != nil {
 		p.tb.Log(err)
 	}
 }
 
 // End will wait for Program to finish or timeout then match and log output.
+// Subsequent calls return the error of the first.
+// Programs are started in their own process group so that the timeout
+// signals also reach their descendants. Processes left in the group after
+// the program exits are reported; these are killed only after a timeout or
+// Quit, so that a program may deliberately background a child.
 func (p *Program) End() (err error) {
 	p.tb.Helper()
+	if p.ended {
+		return p.endErr
+	}
 	tm := time.NewTimer(p.dur)
-	done := make(chan error)
 	sig := syscall.SIGTERM
-	go func() { done <- p.cmd.Wait() }()
+	timedOut := false
 again:
 	select {
-	case err = <-done:
+	case <-p.done:
+		err = p.waitErr
 		tm.Stop()
-		if s := strings.TrimSpace(p.ebuf.String()); len(s) > 0 {
-			err = errors.New(p.ebuf.String())
-			p.ebuf.Reset()
+		for _, lw := range p.lws {
+			lw.Flush()
 		}
-		if err == nil && p.exp != nil && !p.exp.Match(p.obuf.Bytes()) {
-			err = fmt.Errorf("mismatch %q", p.exp)
+		if p.replayed {
+			p.result = p.take.result()
+		} else {
+			p.result = newResult(p.cmd, p.start)
+		}
+		// skip the newline prefaced for pretty logging
+		p.result.Stdout = p.obuf.String()[1:]
+		p.result.Stderr = p.ebuf.String()
+		p.result.reportMetrics(p.tb)
+		err = p.check(err)
+		if err == nil && p.budget != nil {
+			err = p.result.Check(*p.budget)
+		}
+		p.result.transcribe(p.tb, p.netns, err)
+		if p.take != nil && !p.replayed {
+			p.take.Stdin = p.sent.String()
+			p.take.record(p.result)
+			if xerr := save(p.tb.Name()); xerr != nil {
+				p.tb.Log(xerr)
+			}
+		}
+		if (timedOut || p.quit) && p.cmd.Process != nil {
+			p.survivors = reapGroup(p.cmd.Process.Pid)
+			if len(p.survivors) > 0 && (*VV || !p.quiet) {
+				p.tb.Log("killed", p.Pid(), "survivors",
+					p.survivors)
+			}
+		} else if len(p.survivors) > 0 && (*VV || !p.quiet) {
+			p.tb.Log(p.Pid(), "survivors", p.survivors)
 		}
 	case <-tm.C:
 		err = syscall.ETIME
+		timedOut = true
 		if *VV || !p.quiet {
 			p.tb.Log(sig, "process", p.cmd.Process.Pid, p.cmd.Args)
 		}
-		p.cmd.Process.Signal(sig)
+		signalGroup(p.cmd, sig)
 		tm.Reset(3 * time.Second)
 		sig = syscall.SIGKILL
 		goto again
 	}
-	if !p.quiet && (*VV || err != nil) {
+	if !p.quiet && p.stream == nil && (*VV || err != nil) {
 		s := strings.TrimRight(p.obuf.String(), "\n")
 		if len(s) > 0 {
 			p.tb.Log(s)
 		}
 	}
 	p.obuf.Reset()
+	p.ended, p.endErr = true, err
 	return
 }
 
-// Pid returns the program process identifier.
-func (p *Program) Pid() int {
-	return p.cmd.Process.Pid
+// Send writes s to the Stdin of an Interactive Program.
+func (p *Program) Send(s string) error {
+	if p.stdin == nil {
+		return errors.New("not interactive")
+	}
+	if *VVV {
+		p.tb.Helper()
+		p.tb.Logf("%d send %q", p.Pid(), s)
+	}
+	if p.take != nil {
+		p.sent.Write([]byte(s))
+	}
+	_, err := io.WriteString(p.stdin, s)
+	return err
 }
 
-// A Daemon is a background program started and defer stopped from a TestMain.
-type Daemon struct {
-	cmd    *exec.Cmd
-	stdout *bytes.Buffer
-	stderr *bytes.Buffer
+// CloseStdin of an Interactive Program to signal end of input.
+func (p *Program) CloseStdin() error {
+	if p.stdin == nil {
+		return errors.New("not interactive")
+	}
+	return p.stdin.Close()
 }
 
-func (d *Daemon) Pid() int {
-	return d.cmd.Process.Pid
+// Expect waits up to timeout for the Program Stdout, following that
+// consumed by any previous Expect, to match the compiled regex pattern.
+// Expect returns the matched text and any submatches.
+func (p *Program) Expect(re *regexp.Regexp, timeout time.Duration) ([]string,
+	error) {
+	tm := time.NewTimer(timeout)
+	defer tm.Stop()
+	for {
+		b, changed, closed := p.obuf.Since(p.mark)
+		if loc := re.FindSubmatchIndex(b); loc != nil {
+			match := make([]string, len(loc)/2)
+			for i := range match {
+				if loc[2*i] >= 0 {
+					match[i] = string(b[loc[2*i]:loc[2*i+1]])
+				}
+			}
+			p.mark += loc[1]
+			if *VVV {
+				p.tb.Helper()
+				p.tb.Logf("%d expect %q: %q", p.Pid(), re, match[0])
+			}
+			return match, nil
+		}
+		if closed {
+			return nil, fmt.Errorf("expect %q: program exited", re)
+		}
+		select {
+		case <-changed:
+		case <-tm.C:
+			return nil, fmt.Errorf("expect %q: timeout after %v",
+				re, timeout)
+		}
+	}
 }
 
-// Start the daemon program and panic if error.
-func (d *Daemon) Start(args ...string) {
-	d.cmd = exec.Command(args[0], args[1:]...)
-	d.stdout = new(bytes.Buffer)
-	d.stderr = new(bytes.Buffer)
-	d.cmd.Stdout = d.stdout
-	d.cmd.Stderr = d.stderr
-	if *VVV {
-		Log().Output(2, strings.TrimSpace(fmt.Sprintln(args)))
+// Result of the Program after End.
+func (p *Program) Result() *Result {
+	return p.result
+}
+
+// Wait is End that also returns the Program Result.
+func (p *Program) Wait() (*Result, error) {
+	p.tb.Helper()
+	err := p.End()
+	return p.result, err
+}
+
+// check the exit status and output of a finished Program.
+func (p *Program) check(err error) error {
+	r := p.result
+	_, exitErr := err.(*exec.ExitError)
+	switch {
+	case r.Signal != nil:
+		if err == nil {
+			err = fmt.Errorf("signal: %v", r.Signal)
+		}
+	case r.ExitCode < 0:
+		// didn't start
+	case r.ExitCode == p.exit:
+		if exitErr {
+			err = nil
+		}
+	case p.exit != 0:
+		err = fmt.Errorf("exit status %d, expected %d", r.ExitCode,
+			p.exit)
+	case err == nil:
+		err = fmt.Errorf("exit status %d", r.ExitCode)
 	}
-	if err := d.cmd.Start(); err != nil {
-		panic(fmt.Errorf("%v: %v", args, err))
+	if p.stderr != nil {
+		if err == nil && !p.stderr.MatchString(p.ebuf.String()) {
+			err = fmt.Errorf("stderr mismatch %q", p.stderr)
+		}
+	} else if !p.allowStderr {
+		if s := strings.TrimSpace(p.ebuf.String()); len(s) > 0 {
+			err = errors.New(p.ebuf.String())
+			p.ebuf.Reset()
+		}
+	}
+	if err == nil && p.exp != nil && !p.exp.MatchString(p.result.Stdout) {
+		err = fmt.Errorf("mismatch %q", p.exp)
 	}
+	return err
 }
 
-// Stop the running daemon with a TERM, INT, then KILL signal
-func (d *Daemon) Stop() {
-	var err error
-	done := make(chan error)
-	sig := syscall.SIGTERM
-	timeout := 3 * time.Second
-	tm := time.NewTimer(timeout)
-	d.cmd.Process.Signal(sig)
-	go func() { done <- d.cmd.Wait() }()
-again:
-	select {
-	case err = <-done:
-	case <-tm.C:
-		switch sig {
-		case syscall.SIGKILL:
-			Log().Output(2, "won't die!")
-		case syscall.SIGINT:
-			err = syscall.ETIME
-			sig = syscall.SIGKILL
-			timeout *= 2
-			d.cmd.Process.Signal(sig)
-			tm.Reset(timeout)
-			goto again
-		case syscall.SIGTERM:
-			sig = syscall.SIGINT
-			timeout *= 2
-			d.cmd.Process.Signal(sig)
-			tm.Reset(timeout)
-			goto again
-		}
-	}
-	tm.Stop()
-	if err != nil {
-		s := err.Error()
-		for _, b := range []*bytes.Buffer{
-			d.stdout,
-			d.stderr,
-		} {
-			if b.Len() > 0 {
-				s += "\n"
-				s += b.String()
-			}
-		}
-		Log().Output(2, strings.TrimSpace(s))
-	} else if *VV && d.stdout.Len() > 0 {
-		Log().Output(2, d.stdout.String())
+// Pid returns the program process identifier; or 0 if replayed.
+func (p *Program) Pid() int {
+	if p.cmd.Process == nil {
+		return 0
+	}
+	return p.cmd.Process.Pid
+}
+
+// replay the Program output and exit status from the take rather than
+// starting it.
+func (p *Program) replay(take *Take) {
+	p.take = take
+	p.replayed = true
+	io.WriteString(p.cmd.Stdout, take.Stdout)
+	io.WriteString(p.cmd.Stderr, take.Stderr)
+	if p.interactive {
+		p.stdin = nopWriteCloser{ioutil.Discard}
 	}
+	p.obuf.Close()
+	p.ebuf.Close()
+	close(p.done)
 }
 
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
+
 // Run a program - usually from TestMain - and panic if error.
+// See Exec to instead return the Result and error.
 func Run(args ...string) {
 	if *VVV {
 		Log().Output(2, strings.TrimSpace(fmt.Sprintln(args)))
 	}
-	output, err := exec.Command(args[0], args[1:]...).Output()
-	if *VV && len(output) > 0 {
-		Log().Output(2, string(output))
+	r, err := execResult(args)
+	if *VV && r != nil && len(r.Stdout) > 0 {
+		Log().Output(2, r.Stdout)
 	}
 	if err != nil {
 		if *VVV {
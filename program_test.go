@@ -0,0 +1,148 @@
+// Copyright © 2015-2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProgramExit(t *testing.T) {
+	assert := Assert{t}
+	assert.Program("sh", "-c", "exit 0")
+	assert.Program(ExitStatus(2), "sh", "-c", "exit 2")
+	assert.ProgramErr("exit status 1, expected 2",
+		ExitStatus(2), "sh", "-c", "exit 1")
+	assert.ProgramErr("exit status 0, expected 2",
+		ExitStatus(2), "sh", "-c", "exit 0")
+	assert.ProgramErr(regexp.MustCompile("^exit status 3$"),
+		Quiet{}, "sh", "-c", "exit 3")
+}
+
+func TestProgramStderr(t *testing.T) {
+	assert := Assert{t}
+	assert.ProgramErr("warning\n", "sh", "-c", "echo warning >&2")
+	assert.Program(AllowStderr{}, "sh", "-c", "echo warning >&2")
+	assert.Program(Stderr{regexp.MustCompile("^warn")},
+		"sh", "-c", "echo warning >&2")
+	assert.ProgramErr(true, Stderr{regexp.MustCompile("^error")},
+		"sh", "-c", "echo warning >&2")
+	assert.ProgramErr("usage\n", ExitStatus(2),
+		"sh", "-c", "echo usage >&2; exit 2")
+	assert.Program(ExitStatus(2), Stderr{regexp.MustCompile("usage")},
+		"sh", "-c", "echo usage >&2; exit 2")
+}
+
+func TestProgramResult(t *testing.T) {
+	assert := Assert{t}
+	r := assert.Output("sh", "-c", "echo hello; echo world")
+	assert.Equal(r.Stdout, "hello\nworld\n")
+	assert.Equal(r.Stderr, "")
+	assert.True(r.ExitCode == 0 && r.Signal == nil)
+	assert.Equal(r.Args[0], "sh")
+
+	r, err := Exec("sh", "-c", "echo oops >&2; exit 4")
+	assert.NonNil(err)
+	assert.Equal(r.Stderr, "oops\n")
+	assert.True(r.ExitCode == 4)
+}
+
+func TestProgramExpect(t *testing.T) {
+	assert := Assert{t}
+	p, err := Begin(t, Interactive{}, "sh", "-c",
+		`echo ready; while read line; do echo "got $line"; done`)
+	assert.Nil(err)
+	assert.Expect(p, "ready\n", Timeout)
+	assert.Nil(p.Send("one\n"))
+	match := assert.Expect(p, `got (\w+)`, Timeout)
+	assert.Equal(match[1], "one")
+	assert.Nil(p.Send("two\n"))
+	assert.Equal(assert.Expect(p, `got \w+`, Timeout)[0], "got two")
+	_, err = p.Expect(regexp.MustCompile("three"), 100*time.Millisecond)
+	assert.NonNil(err)
+	assert.Nil(p.CloseStdin())
+	assert.Nil(p.End())
+}
+
+func TestProgramGroup(t *testing.T) {
+	assert := Assert{t}
+	// the backgrounded sleep holds Stdout so End times out then kills
+	// the whole group rather than just the shell
+	p, err := Begin(t, Quiet{}, 500*time.Millisecond, "sh", "-c",
+		"sleep 60 & echo $!; wait")
+	assert.Nil(err)
+	assert.NonNil(p.End())
+	pid := strings.TrimSpace(p.Result().Stdout)
+	assert.Match(pid, `^\d+$`)
+	// the orphan may linger as a zombie until reaped by init
+	b, err := ioutil.ReadFile("/proc/" + pid + "/stat")
+	assert.True(os.IsNotExist(err) || strings.Contains(string(b), ") Z "))
+}
+
+func TestProgramBudget(t *testing.T) {
+	assert := Assert{t}
+	r := assert.Output(Budget{Wall: Timeout}, "sh", "-c", "true")
+	assert.True(r.Usage.MaxRSS > 0)
+	assert.ProgramErr(regexp.MustCompile("over budget: wall"), Quiet{},
+		Budget{Wall: time.Millisecond}, "sleep", "0.1")
+}
+
+func TestProgramEnvDir(t *testing.T) {
+	assert := Assert{t}
+	r := assert.Output(Env{"TEST_ENV=value"}, Dir("/"), "sh", "-c",
+		`echo $TEST_ENV; pwd`)
+	assert.Equal(r.Stdout, "value\n/\n")
+	r = assert.Output("sh", "-c", `echo ${TEST_ENV-unset}`)
+	assert.Equal(r.Stdout, "unset\n")
+}
+
+func TestProgramNetns(t *testing.T) {
+	ns := testNetns(t)
+	assert := Assert{t}
+	// /proc/self/net is that of the program, whereas /sys is the host's
+	r := assert.Output(Netns(ns), "cat", "/proc/self/net/dev")
+	assert.That(r.Stdout, AllOf(Contains("d0:"), Not(Contains("eth0:"))))
+	_, err := Begin(t, Netns("no-such-netns"), "true")
+	assert.Error(err, Contains("no-such-netns"))
+}
+
+func TestProgramEndTwice(t *testing.T) {
+	assert := Assert{t}
+	p, err := Begin(t, Quiet{}, "sh", "-c", "echo out; exit 3")
+	assert.Nil(err)
+	err = p.End()
+	assert.Error(err, "exit status 3")
+	p.Quit()
+	r, xerr := p.Wait()
+	assert.True(xerr == err)
+	assert.Equal(r.Stdout, "out\n")
+}
+
+func TestProgramBackground(t *testing.T) {
+	assert := Assert{t}
+	// a deliberately backgrounded child survives a normal exit
+	p, err := Begin(t, Quiet{}, "sh", "-c",
+		"sleep 60 >/dev/null 2>&1 & echo $!")
+	assert.Nil(err)
+	assert.Nil(p.End())
+	pid := strings.TrimSpace(p.Result().Stdout)
+	assert.Equal(fmt.Sprint(len(p.survivors)), "1")
+	assert.Match(p.survivors[0], "^"+pid+" sleep 60$")
+	b, err := ioutil.ReadFile("/proc/" + pid + "/stat")
+	assert.Nil(err)
+	assert.False(strings.Contains(string(b), ") Z "))
+	// until Quit
+	p.Quit()
+	assert.Eventually(func() (interface{}, bool) {
+		b, err := ioutil.ReadFile("/proc/" + pid + "/stat")
+		return string(b), os.IsNotExist(err) ||
+			strings.Contains(string(b), ") Z ")
+	}, time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,199 @@
+// Copyright © 2015-2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+// ReplayDir has the transcripts of -test.record, one per test name, and of
+// programs run from TestMain in TestMain.json.
+const ReplayDir = "testdata/replay"
+
+// The transcripts are relative to the package directory rather than that
+// of any Assert.Dir.
+var packageDir, _ = os.Getwd()
+
+// A Take is the transcribed execution of a Program, Exec or Daemon.
+type Take struct {
+	Args     []string
+	Netns    string `json:",omitempty"`
+	Stdin    string `json:",omitempty"`
+	Stdout   string `json:",omitempty"`
+	Stderr   string `json:",omitempty"`
+	ExitCode int
+	Signal   int `json:",omitempty"`
+}
+
+// result of replaying the take.
+func (take *Take) result() *Result {
+	r := &Result{
+		Args:     take.Args,
+		Stdout:   take.Stdout,
+		Stderr:   take.Stderr,
+		ExitCode: take.ExitCode,
+	}
+	if take.Signal != 0 {
+		r.Signal = syscall.Signal(take.Signal)
+	}
+	return r
+}
+
+// record the result in the take.
+func (take *Take) record(r *Result) {
+	take.Stdout = r.Stdout
+	take.Stderr = r.Stderr
+	take.ExitCode = r.ExitCode
+	if sig, ok := r.Signal.(syscall.Signal); ok {
+		take.Signal = int(sig)
+	}
+}
+
+// a transcript of all Takes for a test name.
+type transcript struct {
+	mutex sync.Mutex
+	fn    string
+	takes []*Take
+	next  int
+}
+
+var transcripts struct {
+	sync.Mutex
+	byName map[string]*transcript
+}
+
+func getTranscript(name string) (*transcript, error) {
+	transcripts.Lock()
+	defer transcripts.Unlock()
+	if tr, found := transcripts.byName[name]; found {
+		return tr, nil
+	}
+	tr := &transcript{
+		fn: filepath.Join(packageDir, ReplayDir,
+			filepath.FromSlash(name)+".json"),
+	}
+	if *ReplayFlag {
+		b, err := ioutil.ReadFile(tr.fn)
+		if err != nil {
+			return nil, err
+		}
+		if err = json.Unmarshal(b, &tr.takes); err != nil {
+			return nil, fmt.Errorf("%s: %v", tr.fn, err)
+		}
+	}
+	if transcripts.byName == nil {
+		transcripts.byName = make(map[string]*transcript)
+	}
+	transcripts.byName[name] = tr
+	return tr, nil
+}
+
+// recording returns a new Take in the named transcript to be saved when
+// done; or nil, nil without -test.record.
+func recording(name string, args []string, netns Netns) (*Take, error) {
+	if !*RecordFlag {
+		return nil, nil
+	}
+	tr, err := getTranscript(name)
+	if err != nil {
+		return nil, err
+	}
+	tr.mutex.Lock()
+	defer tr.mutex.Unlock()
+	take := &Take{Args: args}
+	if !netns.IsDefault() {
+		take.Netns = string(netns)
+	}
+	tr.takes = append(tr.takes, take)
+	return take, nil
+}
+
+// save the named transcript after its last Take is done.
+func save(name string) error {
+	tr, err := getTranscript(name)
+	if err != nil {
+		return err
+	}
+	tr.mutex.Lock()
+	defer tr.mutex.Unlock()
+	// don't escape the <, >, and & of shell args and output
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "\t")
+	if err = enc.Encode(tr.takes); err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(tr.fn), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(tr.fn, buf.Bytes(), 0644)
+}
+
+// replaying returns the next Take of the named transcript after verifying
+// its args and netns; or nil, nil without -test.replay.
+func replaying(name string, args []string, netns Netns) (*Take, error) {
+	if !*ReplayFlag {
+		return nil, nil
+	}
+	tr, err := getTranscript(name)
+	if err != nil {
+		return nil, err
+	}
+	tr.mutex.Lock()
+	defer tr.mutex.Unlock()
+	if tr.next >= len(tr.takes) {
+		return nil, fmt.Errorf("%s: no take for %v", tr.fn, args)
+	}
+	take := tr.takes[tr.next]
+	tr.next++
+	got := argv(args, netns)
+	want := argv(take.Args, Netns(take.Netns))
+	if got != want {
+		return nil, fmt.Errorf("%s: take %d mismatch:\n%s", tr.fn,
+			tr.next, diffArgs(want, got))
+	}
+	return take, nil
+}
+
+// argv is a line per argument preceded by any netns.
+func argv(args []string, netns Netns) string {
+	s := ""
+	if !netns.IsDefault() {
+		s = fmt.Sprintf("netns %q\n", string(netns))
+	}
+	for _, arg := range args {
+		s += fmt.Sprintf("%q\n", arg)
+	}
+	return s
+}
+
+// diffArgs lists the args that differ with - for want, + for got.
+func diffArgs(want, got string) string {
+	wl := strings.Split(strings.TrimSuffix(want, "\n"), "\n")
+	gl := strings.Split(strings.TrimSuffix(got, "\n"), "\n")
+	s := ""
+	for i := 0; i < len(wl) || i < len(gl); i++ {
+		switch {
+		case i >= len(gl):
+			s += "-\t" + wl[i] + "\n"
+		case i >= len(wl):
+			s += "+\t" + gl[i] + "\n"
+		case wl[i] == gl[i]:
+			s += " \t" + wl[i] + "\n"
+		default:
+			s += "-\t" + wl[i] + "\n+\t" + gl[i] + "\n"
+		}
+	}
+	return s
+}
@@ -0,0 +1,48 @@
+// Copyright © 2015-2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReplay(t *testing.T) {
+	assert := Assert{t}
+	dir, err := ioutil.TempDir("", "test-replay")
+	assert.Nil(err)
+	defer os.RemoveAll(dir)
+	defer func(dir string, record, replay bool) {
+		packageDir, *RecordFlag, *ReplayFlag = dir, record, replay
+		transcripts.byName = nil
+	}(packageDir, *RecordFlag, *ReplayFlag)
+	packageDir = dir
+
+	transcripts.byName = nil
+	*RecordFlag, *ReplayFlag = true, false
+	recorded := assert.Output("sh", "-c", "echo '<a&b>' $$")
+	b, err := ioutil.ReadFile(filepath.Join(dir, ReplayDir,
+		t.Name()+".json"))
+	assert.Nil(err)
+	if !strings.Contains(string(b), `"<a&b>`) {
+		t.Fatalf("escaped transcript:\n%s", b)
+	}
+
+	transcripts.byName = nil
+	*RecordFlag, *ReplayFlag = false, true
+	replayed := assert.Output("sh", "-c", "echo '<a&b>' $$")
+	assert.Equal(replayed.Stdout, recorded.Stdout)
+
+	transcripts.byName = nil
+	_, err = Begin(t, "sh", "-c", "echo '<a&c>' $$")
+	assert.Error(err, Contains("take 1 mismatch:\n"+
+		" \t\"sh\"\n"+
+		" \t\"-c\"\n"+
+		"-\t\"echo '<a&b>' $$\"\n"+
+		"+\t\"echo '<a&c>' $$\"\n"))
+}
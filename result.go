@@ -0,0 +1,107 @@
+// Copyright © 2015-2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Result of a finished Program or Exec.
+type Result struct {
+	Args     []string
+	Stdout   string
+	Stderr   string
+	ExitCode int       // -1 if killed by Signal
+	Signal   os.Signal // nil unless killed
+	Duration time.Duration
+	Usage    Usage
+}
+
+func (r *Result) String() string {
+	if r.Signal != nil {
+		return fmt.Sprint(r.Args, " ", r.Signal)
+	}
+	return fmt.Sprint(r.Args, " exit status ", r.ExitCode)
+}
+
+// newResult from a waited command.
+func newResult(cmd *exec.Cmd, start time.Time) *Result {
+	r := &Result{
+		Args:     cmd.Args,
+		ExitCode: -1,
+		Duration: time.Since(start),
+	}
+	if cmd.ProcessState == nil {
+		return r
+	}
+	r.Usage = newUsage(cmd.ProcessState)
+	ws := cmd.ProcessState.Sys().(syscall.WaitStatus)
+	if ws.Signaled() {
+		r.Signal = ws.Signal()
+	} else {
+		r.ExitCode = ws.ExitStatus()
+	}
+	return r
+}
+
+// Exec runs a program to completion and returns its Result. Unlike Run, it
+// doesn't panic; err is that of exec.Cmd.Run.
+func Exec(args ...string) (*Result, error) {
+	if *VVV {
+		Log().Output(2, strings.TrimSpace(fmt.Sprintln(args)))
+	}
+	return execResult(args)
+}
+
+func execResult(args []string) (*Result, error) {
+	if len(args) == 0 {
+		return nil, errors.New("missing command args")
+	}
+	take, err := replaying("TestMain", args, "")
+	if err != nil {
+		return nil, err
+	}
+	if take != nil {
+		r := take.result()
+		return r, r.err()
+	}
+	if take, err = recording("TestMain", args, ""); err != nil {
+		return nil, err
+	}
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	start := time.Now()
+	err = cmd.Run()
+	r := newResult(cmd, start)
+	r.Stdout = stdout.String()
+	r.Stderr = stderr.String()
+	if take != nil {
+		take.record(r)
+		if xerr := save("TestMain"); xerr != nil {
+			Log().Print(xerr)
+		}
+	}
+	return r, err
+}
+
+// err is like that of exec.Cmd.Wait for the Result's status.
+func (r *Result) err() error {
+	if r.Signal != nil {
+		return fmt.Errorf("signal: %v", r.Signal)
+	}
+	if r.ExitCode != 0 {
+		return fmt.Errorf("exit status %d", r.ExitCode)
+	}
+	return nil
+}
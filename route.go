@@ -0,0 +1,283 @@
+// Copyright © 2015-2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package test
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+)
+
+// A Route of a namespace as shown by "ip -j route". As the expectation of
+// Assert.Route, only its non-zero fields are compared.
+type Route struct {
+	Dst      string    `json:"dst"`
+	Type     string    `json:"type,omitempty"`
+	Gateway  string    `json:"gateway,omitempty"`
+	Dev      string    `json:"dev,omitempty"`
+	Protocol string    `json:"protocol,omitempty"`
+	Scope    string    `json:"scope,omitempty"`
+	Prefsrc  string    `json:"prefsrc,omitempty"`
+	Metric   int       `json:"metric,omitempty"`
+	Table    string    `json:"table,omitempty"`
+	Nexthops []Nexthop `json:"nexthops,omitempty"`
+}
+
+// A Nexthop of an ECMP Route.
+type Nexthop struct {
+	Gateway string `json:"gateway,omitempty"`
+	Dev     string `json:"dev,omitempty"`
+	Weight  int    `json:"weight,omitempty"`
+}
+
+func (nh Nexthop) String() string {
+	s := "via " + nh.Gateway + " dev " + nh.Dev
+	if nh.Weight != 0 {
+		s += fmt.Sprint(" weight ", nh.Weight)
+	}
+	return s
+}
+
+func (r Route) String() string {
+	var b strings.Builder
+	if len(r.Type) > 0 && r.Type != "unicast" {
+		b.WriteString(r.Type + " ")
+	}
+	b.WriteString(r.Dst)
+	for _, f := range []struct{ name, value string }{
+		{"via", r.Gateway},
+		{"dev", r.Dev},
+		{"table", r.Table},
+		{"proto", r.Protocol},
+		{"scope", r.Scope},
+		{"src", r.Prefsrc},
+	} {
+		if len(f.value) > 0 {
+			fmt.Fprint(&b, " ", f.name, " ", f.value)
+		}
+	}
+	if r.Metric != 0 {
+		fmt.Fprint(&b, " metric ", r.Metric)
+	}
+	for _, nh := range r.Nexthops {
+		fmt.Fprint(&b, " nexthop ", nh)
+	}
+	return b.String()
+}
+
+// Routes returns all routing tables of the namespace for the address
+// family of the given prefix or address.
+func Routes(netns Netns, prefix string) ([]Route, error) {
+	var routes []Route
+	err := IP(netns, &routes, family(prefix), "route", "show", "table",
+		"all")
+	return routes, err
+}
+
+func family(addr string) string {
+	if strings.Contains(addr, ":") {
+		return "-6"
+	}
+	return "-4"
+}
+
+// canonicalPrefix converts "default" and host addresses to CIDR notation.
+func canonicalPrefix(s string) string {
+	if s == "default" {
+		return s
+	}
+	if !strings.Contains(s, "/") {
+		if ip := net.ParseIP(s); ip != nil {
+			if ip.To4() != nil {
+				return ip.String() + "/32"
+			}
+			return ip.String() + "/128"
+		}
+		return s
+	}
+	_, ipnet, err := net.ParseCIDR(s)
+	if err != nil {
+		return s
+	}
+	if ones, _ := ipnet.Mask.Size(); ones == 0 {
+		return "default"
+	}
+	return ipnet.String()
+}
+
+func sameIP(a, b string) bool {
+	return a == b || net.ParseIP(a).Equal(net.ParseIP(b))
+}
+
+// nexthops of the route; a single path is one next hop.
+func (r Route) nexthops() []Nexthop {
+	if len(r.Nexthops) > 0 || (len(r.Gateway) == 0 && len(r.Dev) == 0) {
+		return r.Nexthops
+	}
+	return []Nexthop{{Gateway: r.Gateway, Dev: r.Dev}}
+}
+
+// Match is true if r has the non-zero fields of want; Nexthops must be the
+// same set, compared without weight unless any is wanted, then defaulting
+// to 1, and an empty Table is the main table.
+func (r Route) Match(want Route) bool {
+	if len(want.Dst) > 0 &&
+		canonicalPrefix(r.Dst) != canonicalPrefix(want.Dst) {
+		return false
+	}
+	table := r.Table
+	if len(table) == 0 {
+		table = "main"
+	}
+	if len(want.Table) == 0 {
+		want.Table = "main"
+	}
+	for _, f := range []struct{ got, want string }{
+		{table, want.Table},
+		{r.Type, want.Type},
+		{r.Dev, want.Dev},
+		{r.Protocol, want.Protocol},
+		{r.Scope, want.Scope},
+	} {
+		if len(f.want) > 0 && f.got != f.want {
+			return false
+		}
+	}
+	if len(want.Gateway) > 0 && !sameIP(r.Gateway, want.Gateway) {
+		return false
+	}
+	if len(want.Prefsrc) > 0 && !sameIP(r.Prefsrc, want.Prefsrc) {
+		return false
+	}
+	if want.Metric != 0 && r.Metric != want.Metric {
+		return false
+	}
+	if len(want.Nexthops) > 0 {
+		return sameNexthops(r.nexthops(), want.Nexthops)
+	}
+	return true
+}
+
+func sameNexthops(got, want []Nexthop) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	weights := false
+	for _, nh := range want {
+		weights = weights || nh.Weight != 0
+	}
+	keys := func(nhs []Nexthop) string {
+		s := make([]string, len(nhs))
+		for i, nh := range nhs {
+			s[i] = net.ParseIP(nh.Gateway).String() + " " + nh.Dev
+			if weights && nh.Weight == 0 {
+				s[i] += " 1"
+			} else if weights {
+				s[i] += fmt.Sprint(" ", nh.Weight)
+			}
+		}
+		sort.Strings(s)
+		return strings.Join(s, ",")
+	}
+	return keys(got) == keys(want)
+}
+
+func routeTable(routes []Route) string {
+	lines := make([]string, len(routes))
+	for i, r := range routes {
+		lines[i] = r.String()
+	}
+	return strings.Join(lines, "\n\t")
+}
+
+// Route asserts that the namespace has a route to prefix in the main table
+// and, if given, that it matches the non-zero fields of want; it returns the
+// route. Failures show the routing tables.
+// Usage:
+//
+//	assert.Route("R1", "10.3.0.0/24", Route{
+//		Protocol: "bgp",
+//		Nexthops: []Nexthop{
+//			{Gateway: "10.1.0.2", Dev: "eth1"},
+//			{Gateway: "10.2.0.2", Dev: "eth2"},
+//		},
+//	})
+func (assert Assert) Route(netns, prefix string, want ...Route) Route {
+	assert.Helper()
+	routes, err := Routes(Netns(netns), prefix)
+	if err != nil {
+		assert.Fatal(err)
+		return Route{}
+	}
+	match := Route{Dst: prefix}
+	if len(want) > 0 {
+		match = want[0]
+		match.Dst = prefix
+	}
+	for _, r := range routes {
+		if r.Match(match) {
+			return r
+		}
+	}
+	assert.Fatalf("%s: no route %v in\n\t%s", Netns(netns), match,
+		routeTable(routes))
+	return Route{}
+}
+
+// NoRoute asserts that the namespace doesn't have a route to prefix that
+// matches the non-zero fields of want, if given.
+func (assert Assert) NoRoute(netns, prefix string, want ...Route) {
+	assert.Helper()
+	routes, err := Routes(Netns(netns), prefix)
+	if err != nil {
+		assert.Fatal(err)
+		return
+	}
+	match := Route{Dst: prefix}
+	if len(want) > 0 {
+		match = want[0]
+		match.Dst = prefix
+	}
+	for _, r := range routes {
+		if r.Match(match) {
+			assert.Fatalf("%s: unexpected route %v", Netns(netns), r)
+			return
+		}
+	}
+}
+
+// RouteGet asserts that the kernel resolves the route to dst, as with
+// "ip route get", and if given, that it matches the non-zero fields of
+// want, other than Dst; it returns the resolved route.
+func (assert Assert) RouteGet(netns, dst string, want ...Route) Route {
+	assert.Helper()
+	var routes []Route
+	err := IP(Netns(netns), &routes, "route", "get", dst)
+	if err == nil && len(routes) == 0 {
+		err = fmt.Errorf("%s: no route to %s", Netns(netns), dst)
+	}
+	if err != nil {
+		assert.Fatal(err)
+		return Route{}
+	}
+	r := routes[0]
+	if len(want) > 0 {
+		match := want[0]
+		match.Dst = ""
+		if len(match.Table) == 0 {
+			// resolved routes don't show their table
+			match.Table = r.Table
+			if len(match.Table) == 0 {
+				match.Table = "main"
+			}
+		}
+		if !r.Match(match) {
+			assert.Fatalf("%s: route to %s is %v, not %v",
+				Netns(netns), dst, r, match)
+		}
+	}
+	return r
+}
@@ -0,0 +1,60 @@
+// Copyright © 2015-2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package test
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"testing"
+)
+
+// testNetns adds a namespace with a veth pair, d0 of 10.9.0.1/24 and d1,
+// that's removed at the end of the test.
+func testNetns(t *testing.T) string {
+	if os.Geteuid() != 0 {
+		t.Skip("needs CAP_SYS_ADMIN")
+	}
+	if _, err := exec.LookPath("ip"); err != nil {
+		t.Skip(err)
+	}
+	assert := Assert{t}
+	ns := fmt.Sprint("test-", os.Getpid())
+	assert.Program("ip", "netns", "add", ns)
+	t.Cleanup(func() { Assert{t}.Program("ip", "netns", "del", ns) })
+	assert.Program("ip", "-n", ns, "link", "add", "d0", "type", "veth",
+		"peer", "name", "d1")
+	assert.Program("ip", "-n", ns, "link", "set", "d0", "up")
+	assert.Program("ip", "-n", ns, "link", "set", "d1", "up")
+	assert.Program("ip", "-n", ns, "address", "add", "10.9.0.1/24",
+		"dev", "d0")
+	return ns
+}
+
+func TestRoute(t *testing.T) {
+	ns := testNetns(t)
+	assert := Assert{t}
+	assert.Program("ip", "-n", ns, "route", "add", "10.10.0.0/24",
+		"nexthop", "via", "10.9.0.2", "nexthop", "via", "10.9.0.3")
+	assert.Program("ip", "-n", ns, "route", "add", "10.11.0.0/24",
+		"via", "10.9.0.2", "metric", "20", "proto", "static")
+
+	assert.Route(ns, "10.9.0.0/24", Route{Dev: "d0", Protocol: "kernel"})
+	assert.Route(ns, "10.10.0.0/24", Route{Nexthops: []Nexthop{
+		{Gateway: "10.9.0.3", Dev: "d0"},
+		{Gateway: "10.9.0.2", Dev: "d0", Weight: 1},
+	}})
+	r := assert.Route(ns, "10.11.0.0/24", Route{Gateway: "10.9.0.2",
+		Metric: 20, Protocol: "static"})
+	assert.Equal(r.String(),
+		"10.11.0.0/24 via 10.9.0.2 dev d0 proto static metric 20")
+	assert.NoRoute(ns, "10.11.0.0/24", Route{Metric: 10})
+	assert.NoRoute(ns, "default")
+	assert.Route(ns, "10.9.0.1", Route{Type: "local", Table: "local"})
+
+	assert.RouteGet(ns, "10.11.0.9", Route{Gateway: "10.9.0.2",
+		Dev: "d0"})
+	assert.Equal(assert.RouteGet(ns, "10.9.0.7").Dev, "d0")
+}
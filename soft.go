@@ -0,0 +1,172 @@
+// Copyright © 2015-2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package test
+
+import (
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"text/tabwriter"
+)
+
+// soft records the failures of a test rather than stopping it.
+type soft struct {
+	testing.TB
+	mutex    sync.Mutex
+	failures []string
+	rows     []string
+	cols     []string
+	cells    map[[2]string]bool
+}
+
+var softs struct {
+	sync.Mutex
+	m map[testing.TB]*soft
+}
+
+// Soft returns an Assert that records each failure with its call site and
+// continues; the failures are reported together when the test ends. The
+// test is marked failed at the first.
+// Usage:
+//
+//	soft := assert.Soft()
+//	for _, addr := range remotes {
+//		soft.Ping(netns, addr)
+//	}
+func (assert Assert) Soft() Assert {
+	if s, ok := assert.TB.(*soft); ok {
+		return Assert{s}
+	}
+	softs.Lock()
+	defer softs.Unlock()
+	if softs.m == nil {
+		softs.m = make(map[testing.TB]*soft)
+	}
+	s, ok := softs.m[assert.TB]
+	if !ok {
+		s = &soft{
+			TB:    assert.TB,
+			cells: make(map[[2]string]bool),
+		}
+		softs.m[assert.TB] = s
+		assert.Cleanup(func() {
+			softs.Lock()
+			delete(softs.m, s.TB)
+			softs.Unlock()
+			s.report()
+		})
+	}
+	return Assert{s}
+}
+
+// Matrix runs f with a soft Assert and records whether it failed in the
+// cell of a summary table, e.g. the reachability of each remote from each
+// host, that's logged when the test ends.
+func (assert Assert) Matrix(row, col string, f func(Assert)) {
+	sa := assert.Soft()
+	s := sa.TB.(*soft)
+	s.mutex.Lock()
+	n := len(s.failures)
+	s.mutex.Unlock()
+	f(sa)
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if !contains(s.rows, row) {
+		s.rows = append(s.rows, row)
+	}
+	if !contains(s.cols, col) {
+		s.cols = append(s.cols, col)
+	}
+	s.cells[[2]string{row, col}] = len(s.failures) == n
+}
+
+func (s *soft) Fatal(args ...interface{}) {
+	s.record(fmt.Sprintln(args...))
+}
+
+func (s *soft) Fatalf(format string, args ...interface{}) {
+	s.record(fmt.Sprintf(format, args...))
+}
+
+func (s *soft) FailNow() {
+	s.record("failed")
+}
+
+func (s *soft) Failed() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return len(s.failures) > 0 || s.TB.Failed()
+}
+
+func (s *soft) record(msg string) {
+	msg = callSite() + ": " + strings.TrimRight(msg, "\n")
+	s.mutex.Lock()
+	s.failures = append(s.failures, msg)
+	s.mutex.Unlock()
+	s.Fail()
+}
+
+func (s *soft) report() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if len(s.rows) > 0 {
+		var b strings.Builder
+		w := tabwriter.NewWriter(&b, 0, 8, 2, ' ', 0)
+		fmt.Fprint(w, "\t", strings.Join(s.cols, "\t"), "\n")
+		for _, row := range s.rows {
+			fmt.Fprint(w, row)
+			for _, col := range s.cols {
+				ok, found := s.cells[[2]string{row, col}]
+				switch {
+				case !found:
+					fmt.Fprint(w, "\t-")
+				case ok:
+					fmt.Fprint(w, "\tok")
+				default:
+					fmt.Fprint(w, "\tFAIL")
+				}
+			}
+			fmt.Fprint(w, "\n")
+		}
+		w.Flush()
+		s.TB.Log("\n" + b.String())
+	}
+	if len(s.failures) > 0 {
+		s.TB.Errorf("%d failures:\n\t%s", len(s.failures),
+			strings.Join(s.failures, "\n\t"))
+	}
+}
+
+// callSite is the file:line of the first caller outside of this package's
+// non-test sources.
+func callSite() string {
+	_, self, _, _ := runtime.Caller(0)
+	dir := filepath.Dir(self)
+	pc := make([]uintptr, 32)
+	frames := runtime.CallersFrames(pc[:runtime.Callers(2, pc)])
+	for {
+		frame, more := frames.Next()
+		if filepath.Dir(frame.File) != dir ||
+			strings.HasSuffix(frame.File, "_test.go") {
+			return fmt.Sprint(filepath.Base(frame.File), ":",
+				frame.Line)
+		}
+		if !more {
+			return "?"
+		}
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, x := range list {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,42 @@
+// Copyright © 2015-2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package test
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSoft(t *testing.T) {
+	tb := &fakeTB{TB: t}
+	soft := Assert{tb}.Soft()
+	soft.Equal("a", "b")
+	soft.Program("false")
+	soft.Program("true")
+	if !tb.failed || !soft.Failed() {
+		t.Fatal("not failed")
+	}
+	for _, row := range []string{"h1", "h2"} {
+		for _, col := range []string{"r1", "r2"} {
+			Assert{tb}.Matrix(row, col, func(assert Assert) {
+				assert.True(row != "h2" || col != "r1")
+			})
+		}
+	}
+	for _, f := range tb.cleanups {
+		f()
+	}
+	out := strings.Join(tb.out, "\n")
+	for _, s := range []string{
+		"3 failures:",
+		"soft_test.go:15: \"a\"\n\t!= \"b\"",
+		"soft_test.go:16: exit status 1",
+		"h2  FAIL  ok",
+	} {
+		if !strings.Contains(out, s) {
+			t.Errorf("missing %q in:\n%s", s, out)
+		}
+	}
+}
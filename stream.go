@@ -0,0 +1,62 @@
+// Copyright © 2015-2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package test
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// Stream logs each line of Program output as it's received rather than
+// after End; output is still buffered for matching.
+type Stream struct {
+	// Log is the line sink; nil is the Program's testing.TB Log.
+	Log func(args ...interface{})
+}
+
+// lineWriter copies output to w and logs each complete line with a prefix
+// of the process identifier and stream name.
+type lineWriter struct {
+	mutex sync.Mutex
+	w     io.Writer
+	log   func(args ...interface{})
+	pid   func() int
+	name  string
+	buf   []byte
+}
+
+func (lw *lineWriter) Write(b []byte) (int, error) {
+	n, err := lw.w.Write(b)
+	lw.mutex.Lock()
+	defer lw.mutex.Unlock()
+	lw.buf = append(lw.buf, b[:n]...)
+	for {
+		i := bytes.IndexByte(lw.buf, '\n')
+		if i < 0 {
+			break
+		}
+		lw.output(string(lw.buf[:i]))
+		lw.buf = lw.buf[i+1:]
+	}
+	return n, err
+}
+
+// Flush logs any remaining partial line.
+func (lw *lineWriter) Flush() {
+	lw.mutex.Lock()
+	defer lw.mutex.Unlock()
+	if len(lw.buf) > 0 {
+		lw.output(string(lw.buf))
+		lw.buf = lw.buf[:0]
+	}
+}
+
+func (lw *lineWriter) output(line string) {
+	lw.log(fmt.Sprint(lw.pid(), " ", lw.name, ": ",
+		strings.TrimRight(line, "\r")))
+}
@@ -0,0 +1,55 @@
+// Copyright © 2015-2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package test
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestStream(t *testing.T) {
+	assert := Assert{t}
+	var mutex sync.Mutex
+	var lines []string
+	log := func(args ...interface{}) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		lines = append(lines, fmt.Sprint(args...))
+	}
+	p, err := Begin(t, Stream{Log: log}, AllowStderr{}, "sh", "-c",
+		`echo one; echo two >&2; printf 'partial\r'`)
+	assert.Nil(err)
+	pid := p.Pid()
+	assert.Nil(p.End())
+	mutex.Lock()
+	defer mutex.Unlock()
+	// stdout and stderr are copied concurrently
+	sort.Strings(lines)
+	assert.Equal(strings.Join(lines, "\n"), fmt.Sprintf(`%[1]d stderr: two
+%[1]d stdout: one
+%[1]d stdout: partial`, pid))
+	assert.Equal(p.Result().Stdout, "one\npartial\r")
+}
+
+func TestStreamFlag(t *testing.T) {
+	assert := Assert{t}
+	defer func(stream bool) { *StreamFlag = stream }(*StreamFlag)
+	*StreamFlag = true
+	p, err := Begin(t, Quiet{}, "true")
+	assert.Nil(err)
+	assert.Nil(p.End())
+	assert.True(p.stream == nil)
+	p, err = Begin(t, Quiet{}, Stream{}, "true")
+	assert.Nil(err)
+	assert.Nil(p.End())
+	assert.True(p.stream != nil)
+	p, err = Begin(t, "true")
+	assert.Nil(err)
+	assert.Nil(p.End())
+	assert.True(p.stream != nil)
+}
@@ -6,6 +6,7 @@ package test
 
 import (
 	"io"
+	"sync"
 	"testing"
 )
 
@@ -38,7 +39,10 @@ func (tests Tests) Test(t *testing.T) {
 		}
 		name := v.String()
 		if suite, ok := v.(Suite); ok {
-			t.Run(name, suite.Test)
+			t.Run(name, func(t *testing.T) {
+				suite.Test(t)
+				pruneArtifacts(t)
+			})
 		} else {
 			t.Run(name, func(t *testing.T) {
 				t.Helper()
@@ -49,6 +53,8 @@ func (tests Tests) Test(t *testing.T) {
 				if t.Skipped() || t.Failed() {
 					return
 				}
+				defer running(t)()
+				defer pruneArtifacts(t)
 				v.Test(t)
 				if t.Failed() {
 					terr(t, Pause.Prompt(v, " FAILED"))
@@ -68,3 +74,35 @@ func terr(t *testing.T, err error) {
 		}
 	}
 }
+
+// current is the innermost running Tester for failures detected outside of
+// its goroutine, e.g. by Daemon supervision.
+var current struct {
+	sync.Mutex
+	t testing.TB
+}
+
+// running sets the current test and returns a func to restore the previous.
+func running(t testing.TB) func() {
+	current.Lock()
+	defer current.Unlock()
+	prev := current.t
+	current.t = t
+	return func() {
+		current.Lock()
+		defer current.Unlock()
+		current.t = prev
+	}
+}
+
+// failRunning marks the current test as failed; or, if there isn't one,
+// just logs the reason.
+func failRunning(reason string) {
+	current.Lock()
+	defer current.Unlock()
+	if current.t != nil {
+		current.t.Error(reason)
+	} else {
+		Log().Print(reason)
+	}
+}
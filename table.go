@@ -0,0 +1,397 @@
+// Copyright © 2015-2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package test
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// A Row maps column names to cell text.
+type Row map[string]string
+
+// A Table is the list of rows parsed from program output.
+type Table []Row
+
+// Where selects the rows of a Table with these cells; each value is either
+// the exact cell text or a Matcher.
+type Where map[string]interface{}
+
+// ParseTable parses tabular output like that of `goes`, `ip -br link`,
+// `ip -s link`, or `ethtool -S`.
+//
+// A listing of "KEY: VALUE" lines, like `ethtool -S`, is a single Row.
+// Stanzas, like those of `ip -s link`, are a Row per unindented heading
+// line with cells named by the label and header of each indented table,
+// e.g. "RX bytes" and "TX errors".
+// Output without a header, like `ip -br link`, needs the column names; each
+// line is split by whitespace with any excess fields in the last column.
+// Otherwise the first line is the header of column names; each following
+// line with as many fields as there are names is split by whitespace, and
+// others are split by their alignment to the header, so that a row may
+// have empty or multi-word cells. See ParseRecords for output of "KEY VALUE"
+// pairs like `bridge fdb`.
+func ParseTable(s string, columns ...string) Table {
+	var lines []string
+	for _, line := range strings.Split(s, "\n") {
+		if len(strings.TrimSpace(line)) > 0 {
+			lines = append(lines, strings.TrimRight(line, " \t\r"))
+		}
+	}
+	if len(lines) == 0 {
+		return nil
+	}
+	if len(columns) > 0 {
+		table := make(Table, 0, len(lines))
+		for _, line := range lines {
+			row := make(Row)
+			fields := strings.Fields(line)
+			for i, col := range columns {
+				switch {
+				case i >= len(fields):
+					row[col] = ""
+				case i == len(columns)-1:
+					row[col] = strings.Join(fields[i:], " ")
+				default:
+					row[col] = fields[i]
+				}
+			}
+			table = append(table, row)
+		}
+		return table
+	}
+	if isKeyValue(lines) {
+		return Table{ParseKeyValue(s)}
+	}
+	if isStanzas(lines) {
+		return parseStanzas(lines)
+	}
+	header := newHeader(lines[0])
+	table := make(Table, 0, len(lines)-1)
+	for _, line := range lines[1:] {
+		table = append(table, header.row(line))
+	}
+	return table
+}
+
+// A header of column names may follow a label, like the "RX:" of
+// `ip -s link`, that prefixes each name rather than naming a column.
+type header struct {
+	label string
+	spans []cellSpan
+}
+
+func newHeader(line string) header {
+	spans := fieldSpans(line)
+	for i := 0; i < 2 && i < len(spans)-1; i++ {
+		if strings.HasSuffix(spans[i].text, ":") {
+			var label []string
+			for _, f := range spans[:i+1] {
+				label = append(label, f.text)
+			}
+			return header{
+				label: strings.TrimSuffix(strings.Join(label,
+					" "), ":"),
+				spans: spans[i+1:],
+			}
+		}
+	}
+	return header{spans: spans}
+}
+
+func (h header) name(i int) string {
+	if len(h.label) > 0 {
+		return h.label + " " + h.spans[i].text
+	}
+	return h.spans[i].text
+}
+
+// row splits the line by whitespace if it has a field per column;
+// otherwise, by its alignment to the header.
+func (h header) row(line string) Row {
+	row := make(Row)
+	spans := fieldSpans(line)
+	if len(spans) == len(h.spans) {
+		for i, f := range spans {
+			row[h.name(i)] = f.text
+		}
+		return row
+	}
+	for _, f := range spans {
+		col := h.name(f.column(h.spans))
+		if len(row[col]) > 0 {
+			row[col] += " "
+		}
+		row[col] += f.text
+	}
+	return row
+}
+
+// isStanzas is true if the lines are indented below unindented headings
+// with labeled headers like those of `ip -s link`.
+func isStanzas(lines []string) bool {
+	if indented(lines[0]) {
+		return false
+	}
+	for _, line := range lines[1:] {
+		if indented(line) && len(newHeader(line).label) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func indented(line string) bool {
+	return line[0] == ' ' || line[0] == '\t'
+}
+
+// parseStanzas returns a Row per unindented heading, kept in the "heading"
+// cell, with the labeled cells of each indented header and following line.
+// Other indented lines are ignored.
+func parseStanzas(lines []string) Table {
+	var table Table
+	var row Row
+	for i := 0; i < len(lines); i++ {
+		if !indented(lines[i]) {
+			row = Row{"heading": lines[i]}
+			table = append(table, row)
+			continue
+		}
+		h := newHeader(lines[i])
+		if len(h.label) == 0 || i+1 == len(lines) ||
+			!indented(lines[i+1]) {
+			continue
+		}
+		i++
+		for col, cell := range h.row(lines[i]) {
+			row[col] = cell
+		}
+	}
+	return table
+}
+
+// ParseKeyValue parses a listing of "KEY: VALUE" lines into a Row; lines
+// without a colon are ignored.
+func ParseKeyValue(s string) Row {
+	row := make(Row)
+	for _, line := range strings.Split(s, "\n") {
+		i := strings.IndexByte(line, ':')
+		if i < 0 {
+			continue
+		}
+		row[strings.TrimSpace(line[:i])] =
+			strings.TrimSpace(line[i+1:])
+	}
+	return row
+}
+
+// ParseRecords parses headerless output of a record per line like that of
+// `bridge fdb`, `ip neigh`, or `ip route`. The leading fields are named by
+// columns, the rest are "KEY VALUE" pairs of the given keys, or flags that
+// are listed in the "flags" cell. For example,
+//
+//	ParseRecords("02:46:8a:00:02:05 dev xeth1 master br0 permanent",
+//		[]string{"mac"}, "dev", "vlan", "master")
+//
+// is the Row{"mac": "02:46:8a:00:02:05", "dev": "xeth1", "master": "br0",
+// "flags": "permanent"}.
+func ParseRecords(s string, columns []string, keys ...string) Table {
+	isKey := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		isKey[key] = true
+	}
+	var table Table
+	for _, line := range strings.Split(s, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		row := make(Row)
+		for i, col := range columns {
+			if i < len(fields) {
+				row[col] = fields[i]
+			}
+		}
+		var flags []string
+		for i := len(columns); i < len(fields); i++ {
+			if isKey[fields[i]] && i+1 < len(fields) {
+				row[fields[i]] = fields[i+1]
+				i++
+			} else {
+				flags = append(flags, fields[i])
+			}
+		}
+		row["flags"] = strings.Join(flags, " ")
+		table = append(table, row)
+	}
+	return table
+}
+
+func isKeyValue(lines []string) bool {
+	for _, line := range lines {
+		if !strings.Contains(line, ": ") && !strings.HasSuffix(line, ":") {
+			return false
+		}
+	}
+	return true
+}
+
+type cellSpan struct {
+	text       string
+	start, end int
+}
+
+func fieldSpans(line string) []cellSpan {
+	var spans []cellSpan
+	start := -1
+	for i := 0; i <= len(line); i++ {
+		blank := i == len(line) || line[i] == ' ' || line[i] == '\t'
+		switch {
+		case blank && start >= 0:
+			spans = append(spans, cellSpan{line[start:i], start, i})
+			start = -1
+		case !blank && start < 0:
+			start = i
+		}
+	}
+	return spans
+}
+
+// column returns the index of the header field that f is right-aligned with,
+// like a counter; otherwise, that which f overlaps most or, if none, the
+// nearest.
+func (f cellSpan) column(header []cellSpan) int {
+	for i, h := range header {
+		if f.end == h.end {
+			return i
+		}
+	}
+	best, bestScore := 0, -1<<31
+	for i, h := range header {
+		score := minInt(f.end, h.end) - maxInt(f.start, h.start)
+		if score > bestScore {
+			best, bestScore = i, score
+		}
+	}
+	return best
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// Match is true if the row has all of the given cells.
+func (row Row) Match(where Where) bool {
+	for col, v := range where {
+		cell, found := row[col]
+		if !found {
+			return false
+		}
+		switch t := v.(type) {
+		case Matcher:
+			if !t.MatchString(cell) {
+				return false
+			}
+		default:
+			if cell != fmt.Sprint(t) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Find the first row with the given cells.
+func (table Table) Find(where Where) (Row, bool) {
+	for _, row := range table {
+		if row.Match(where) {
+			return row, true
+		}
+	}
+	return nil, false
+}
+
+func (row Row) String() string {
+	cols := make([]string, 0, len(row))
+	for col := range row {
+		cols = append(cols, col)
+	}
+	sort.Strings(cols)
+	for i, col := range cols {
+		cols[i] = fmt.Sprintf("%s=%q", col, row[col])
+	}
+	return "{" + strings.Join(cols, ", ") + "}"
+}
+
+func (where Where) String() string {
+	cols := make([]string, 0, len(where))
+	for col := range where {
+		cols = append(cols, col)
+	}
+	sort.Strings(cols)
+	for i, col := range cols {
+		if m, ok := where[col].(Matcher); ok {
+			cols[i] = fmt.Sprintf("%s %v", col, m)
+		} else {
+			cols[i] = fmt.Sprintf("%s=%q", col, where[col])
+		}
+	}
+	return "{" + strings.Join(cols, ", ") + "}"
+}
+
+// Table asserts that the table, or program output to parse with ParseTable,
+// has a row with the given cells and returns the first.
+// Usage:
+//
+//	r := assert.Output(Netns(ns), "bridge", "fdb", "show", "br0")
+//	fdb := ParseRecords(r.Stdout, []string{"mac"}, "dev", "vlan", "master")
+//	assert.Table(fdb, Where{"mac": mac, "dev": "xeth1",
+//		"flags": Not(Contains("permanent"))})
+func (assert Assert) Table(v interface{}, where Where) Row {
+	assert.Helper()
+	table := toTable(assert, v)
+	row, found := table.Find(where)
+	if !found {
+		lines := make([]string, len(table))
+		for i, row := range table {
+			lines[i] = row.String()
+		}
+		assert.Fatalf("no row %v in\n\t%s", where,
+			strings.Join(lines, "\n\t"))
+	}
+	return row
+}
+
+// NoRow asserts that the table, or program output to parse with ParseTable,
+// doesn't have a row with the given cells.
+func (assert Assert) NoRow(v interface{}, where Where) {
+	assert.Helper()
+	if row, found := toTable(assert, v).Find(where); found {
+		assert.Fatalf("unexpected row %v", row)
+	}
+}
+
+func toTable(assert Assert, v interface{}) Table {
+	assert.Helper()
+	switch t := v.(type) {
+	case Table:
+		return t
+	case string:
+		return ParseTable(t)
+	}
+	assert.Fatalf("not a table: %T", v)
+	return nil
+}
@@ -0,0 +1,134 @@
+// Copyright © 2015-2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package test
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTable(t *testing.T) {
+	assert := Assert{t}
+	table := ParseTable(`
+Interface     State  Link  Speed   Media
+xeth1         up     true  100g    copper
+xeth2         down         10g
+xeth3         up     false 25g     fiber
+`)
+	assert.DeepEqual(table[1], Row{
+		"Interface": "xeth2",
+		"State":     "down",
+		"Speed":     "10g",
+	})
+	assert.Equal(assert.Table(table, Where{"Link": "true"})["Interface"],
+		"xeth1")
+	assert.Table(table, Where{"Interface": "xeth3", "Speed": Regexp("g$")})
+	assert.NoRow(table, Where{"State": "down", "Link": "true"})
+
+	assert.Table(`NIC statistics:
+     rx_packets: 1024
+     tx_packets: 0
+`, Where{"rx_packets": Gt(0), "tx_packets": "0"})
+
+	fields := ParseTable("mac dev state\n" +
+		"02:46:8a:00:02:05 xeth1 permanent\n")
+	assert.DeepEqual(fields, Table{{
+		"mac":   "02:46:8a:00:02:05",
+		"dev":   "xeth1",
+		"state": "permanent",
+	}})
+}
+
+func TestHeaderless(t *testing.T) {
+	assert := Assert{t}
+	brief := ParseTable(`
+lo               UNKNOWN        00:00:00:00:00:00 <LOOPBACK,UP,LOWER_UP>
+xeth1            DOWN           02:46:8a:00:02:05 <NO-CARRIER,BROADCAST,UP>
+`, "ifname", "state", "mac", "flags")
+	assert.DeepEqual(brief[1], Row{
+		"ifname": "xeth1",
+		"state":  "DOWN",
+		"mac":    "02:46:8a:00:02:05",
+		"flags":  "<NO-CARRIER,BROADCAST,UP>",
+	})
+
+	fdb := ParseRecords(`
+02:46:8a:00:02:05 dev xeth1 vlan 10 master br0 permanent
+02:46:8a:00:02:06 dev xeth2 master br0
+33:33:00:00:00:01 dev br0 self permanent
+`, []string{"mac"}, "dev", "vlan", "master")
+	assert.DeepEqual(fdb[0], Row{
+		"mac":    "02:46:8a:00:02:05",
+		"dev":    "xeth1",
+		"vlan":   "10",
+		"master": "br0",
+		"flags":  "permanent",
+	})
+	row := assert.Table(fdb, Where{"master": "br0",
+		"flags": Not(Contains("permanent"))})
+	assert.Equal(row["dev"], "xeth2")
+	assert.Table(fdb, Where{"dev": "br0", "flags": "self permanent"})
+}
+
+func TestStanzas(t *testing.T) {
+	assert := Assert{t}
+	links := ParseTable(`1: lo: <LOOPBACK,UP,LOWER_UP> mtu 65536 qdisc noqueue state UNKNOWN mode DEFAULT group default qlen 1000
+    link/loopback 00:00:00:00:00:00 brd 00:00:00:00:00:00
+    RX:  bytes packets errors dropped  missed   mcast           
+     104589926   12389      0       0       0       0 
+    TX:  bytes packets errors dropped carrier collsns           
+     104589926   12389      0       0       0       0 
+2: xeth1@eth0: <BROADCAST,MULTICAST,UP,LOWER_UP> mtu 1500 qdisc noqueue state UP mode DEFAULT group default qlen 1000
+    link/ether 02:46:8a:00:02:05 brd ff:ff:ff:ff:ff:ff link-netnsid 0
+    RX:  bytes packets errors dropped  missed   mcast           
+          1296      16      0       2       0       0 
+    RX errors:  length    crc   frame    fifo overrun
+                     0      0       0       0       0 
+    TX:  bytes packets errors dropped carrier collsns           
+           796      10      0       0       0       0 
+`)
+	assert.True(len(links) == 2)
+	row := assert.Table(links, Where{
+		"heading":    Regexp("^[0-9]+: xeth1[:@]"),
+		"RX dropped": Gt(0),
+	})
+	assert.Equal(row["RX bytes"], "1296")
+	assert.Equal(row["RX errors crc"], "0")
+	assert.Equal(row["TX collsns"], "0")
+	assert.Equal(links[0]["TX packets"], "12389")
+
+	// older iproute2 aligns the counters left
+	old := ParseTable(`3: eth0: <BROADCAST,MULTICAST,UP,LOWER_UP> mtu 1500 qdisc mq state UP mode DEFAULT group default qlen 1000
+    link/ether 52:54:00:12:34:56 brd ff:ff:ff:ff:ff:ff
+    RX: bytes  packets  errors  dropped overrun mcast
+    9876       54       0       0       0       3
+    TX: bytes  packets  errors  dropped carrier collsns
+    5432       21       0       0       0       0
+`)
+	assert.Equal(old[0]["RX mcast"], "3")
+	assert.Equal(old[0]["TX bytes"], "5432")
+
+	// right-aligned counters with an empty cell
+	stats := ParseTable(`
+RX:  bytes packets errors
+       123       4
+`)
+	assert.DeepEqual(stats, Table{{
+		"RX bytes":   "123",
+		"RX packets": "4",
+	}})
+}
+
+func TestTableFailure(t *testing.T) {
+	assert := Assert{t}
+	tb := &fakeTB{TB: t}
+	Assert{tb}.Table("a b\n1 2\n3 4\n", Where{"a": "5"})
+	assert.Equal(strings.Join(tb.out, "\n"), `no row {a="5"} in
+	{a="1", b="2"}
+	{a="3", b="4"}`)
+	tb = &fakeTB{TB: t}
+	Assert{tb}.NoRow(42, Where{"a": "1"})
+	assert.Equal(strings.Join(tb.out, "\n"), "not a table: int")
+}
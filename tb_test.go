@@ -0,0 +1,38 @@
+// Copyright © 2015-2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package test
+
+import (
+	"fmt"
+	"testing"
+)
+
+// fakeTB captures the failures and cleanups of a test. Unlike testing.T,
+// its Fatal and Fatalf don't exit the test.
+type fakeTB struct {
+	testing.TB
+	failed   bool
+	out      []string
+	cleanups []func()
+}
+
+func (tb *fakeTB) Fail()        { tb.failed = true }
+func (tb *fakeTB) Failed() bool { return tb.failed }
+func (tb *fakeTB) Log(args ...interface{}) {
+	tb.out = append(tb.out, fmt.Sprint(args...))
+}
+func (tb *fakeTB) Error(args ...interface{}) {
+	tb.failed = true
+	tb.out = append(tb.out, fmt.Sprint(args...))
+}
+func (tb *fakeTB) Errorf(format string, args ...interface{}) {
+	tb.failed = true
+	tb.out = append(tb.out, fmt.Sprintf(format, args...))
+}
+func (tb *fakeTB) Fatal(args ...interface{}) { tb.Error(args...) }
+func (tb *fakeTB) Fatalf(format string, args ...interface{}) {
+	tb.Errorf(format, args...)
+}
+func (tb *fakeTB) Cleanup(f func()) { tb.cleanups = append(tb.cleanups, f) }
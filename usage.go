@@ -0,0 +1,86 @@
+// Copyright © 2015-2019 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package test
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// Usage is the resource accounting of a finished program, including that of
+// its waited descendants.
+type Usage struct {
+	User   time.Duration // CPU time
+	System time.Duration // CPU time
+	MaxRSS int64         // bytes
+	// Voluntary and involuntary context switches
+	Nvcsw, Nivcsw int64
+}
+
+func newUsage(ps *os.ProcessState) Usage {
+	var u Usage
+	if ps == nil {
+		return u
+	}
+	u.User = ps.UserTime()
+	u.System = ps.SystemTime()
+	if ru, ok := ps.SysUsage().(*syscall.Rusage); ok {
+		u.MaxRSS = int64(ru.Maxrss) * 1024
+		u.Nvcsw = int64(ru.Nvcsw)
+		u.Nivcsw = int64(ru.Nivcsw)
+	}
+	return u
+}
+
+func (u Usage) String() string {
+	return fmt.Sprintf("user %v sys %v maxrss %dKiB ctxsw %d/%d",
+		u.User, u.System, u.MaxRSS>>10, u.Nvcsw, u.Nivcsw)
+}
+
+// Budget is a Program option to fail if it exceeds any of these non-zero
+// resource limits.
+type Budget struct {
+	Wall   time.Duration
+	CPU    time.Duration // User + System
+	MaxRSS int64         // bytes
+}
+
+// Check returns an error describing any Budget overrun of the Result.
+func (r *Result) Check(b Budget) error {
+	var over []string
+	if b.Wall != 0 && r.Duration > b.Wall {
+		over = append(over, fmt.Sprint("wall ", r.Duration, " > ",
+			b.Wall))
+	}
+	if cpu := r.Usage.User + r.Usage.System; b.CPU != 0 && cpu > b.CPU {
+		over = append(over, fmt.Sprint("cpu ", cpu, " > ", b.CPU))
+	}
+	if b.MaxRSS != 0 && r.Usage.MaxRSS > b.MaxRSS {
+		over = append(over, fmt.Sprint("maxrss ", r.Usage.MaxRSS,
+			" > ", b.MaxRSS))
+	}
+	if len(over) > 0 {
+		return fmt.Errorf("%v over budget: %s", r.Args,
+			strings.Join(over, ", "))
+	}
+	return nil
+}
+
+// reportMetrics of the Result if run by a Benchmark.
+func (r *Result) reportMetrics(tb testing.TB) {
+	b, ok := tb.(*testing.B)
+	if !ok {
+		return
+	}
+	b.ReportMetric(float64(r.Duration.Nanoseconds()), "wall-ns")
+	b.ReportMetric(float64(r.Usage.User.Nanoseconds()), "user-ns")
+	b.ReportMetric(float64(r.Usage.System.Nanoseconds()), "sys-ns")
+	b.ReportMetric(float64(r.Usage.MaxRSS), "maxrss-B")
+	b.ReportMetric(float64(r.Usage.Nvcsw+r.Usage.Nivcsw), "ctxsw")
+}